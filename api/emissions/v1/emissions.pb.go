@@ -0,0 +1,566 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.2
+// source: api/emissions/v1/emissions.proto
+
+package emissionsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type MeasureRow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Country     string `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	Channel     string `protobuf:"bytes,2,opt,name=channel,proto3" json:"channel,omitempty"`
+	Impressions int64  `protobuf:"varint,3,opt,name=impressions,proto3" json:"impressions,omitempty"`
+	InventoryId string `protobuf:"bytes,4,opt,name=inventory_id,json=inventoryId,proto3" json:"inventory_id,omitempty"`
+	UtcDatetime string `protobuf:"bytes,5,opt,name=utc_datetime,json=utcDatetime,proto3" json:"utc_datetime,omitempty"`
+	// is_priority pins the result in the cache; it requires the
+	// emissions:priority scope.
+	IsPriority bool `protobuf:"varint,6,opt,name=is_priority,json=isPriority,proto3" json:"is_priority,omitempty"`
+}
+
+func (x *MeasureRow) Reset() {
+	*x = MeasureRow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_emissions_v1_emissions_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MeasureRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeasureRow) ProtoMessage() {}
+
+func (x *MeasureRow) ProtoReflect() protoreflect.Message {
+	mi := &file_api_emissions_v1_emissions_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeasureRow.ProtoReflect.Descriptor instead.
+func (*MeasureRow) Descriptor() ([]byte, []int) {
+	return file_api_emissions_v1_emissions_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MeasureRow) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *MeasureRow) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *MeasureRow) GetImpressions() int64 {
+	if x != nil {
+		return x.Impressions
+	}
+	return 0
+}
+
+func (x *MeasureRow) GetInventoryId() string {
+	if x != nil {
+		return x.InventoryId
+	}
+	return ""
+}
+
+func (x *MeasureRow) GetUtcDatetime() string {
+	if x != nil {
+		return x.UtcDatetime
+	}
+	return ""
+}
+
+func (x *MeasureRow) GetIsPriority() bool {
+	if x != nil {
+		return x.IsPriority
+	}
+	return false
+}
+
+type MeasureRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rows []*MeasureRow `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+}
+
+func (x *MeasureRequest) Reset() {
+	*x = MeasureRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_emissions_v1_emissions_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MeasureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeasureRequest) ProtoMessage() {}
+
+func (x *MeasureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_emissions_v1_emissions_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeasureRequest.ProtoReflect.Descriptor instead.
+func (*MeasureRequest) Descriptor() ([]byte, []int) {
+	return file_api_emissions_v1_emissions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MeasureRequest) GetRows() []*MeasureRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type MeasureRowResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PropertyId        int64   `protobuf:"varint,1,opt,name=property_id,json=propertyId,proto3" json:"property_id,omitempty"`
+	PropertyName      string  `protobuf:"bytes,2,opt,name=property_name,json=propertyName,proto3" json:"property_name,omitempty"`
+	TotalEmissions    float64 `protobuf:"fixed64,3,opt,name=total_emissions,json=totalEmissions,proto3" json:"total_emissions,omitempty"`
+	Cached            bool    `protobuf:"varint,4,opt,name=cached,proto3" json:"cached,omitempty"`
+	InventoryCoverage string  `protobuf:"bytes,5,opt,name=inventory_coverage,json=inventoryCoverage,proto3" json:"inventory_coverage,omitempty"`
+}
+
+func (x *MeasureRowResult) Reset() {
+	*x = MeasureRowResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_emissions_v1_emissions_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MeasureRowResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeasureRowResult) ProtoMessage() {}
+
+func (x *MeasureRowResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_emissions_v1_emissions_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeasureRowResult.ProtoReflect.Descriptor instead.
+func (*MeasureRowResult) Descriptor() ([]byte, []int) {
+	return file_api_emissions_v1_emissions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MeasureRowResult) GetPropertyId() int64 {
+	if x != nil {
+		return x.PropertyId
+	}
+	return 0
+}
+
+func (x *MeasureRowResult) GetPropertyName() string {
+	if x != nil {
+		return x.PropertyName
+	}
+	return ""
+}
+
+func (x *MeasureRowResult) GetTotalEmissions() float64 {
+	if x != nil {
+		return x.TotalEmissions
+	}
+	return 0
+}
+
+func (x *MeasureRowResult) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *MeasureRowResult) GetInventoryCoverage() string {
+	if x != nil {
+		return x.InventoryCoverage
+	}
+	return ""
+}
+
+type MeasureResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RequestId      string              `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	TotalEmissions float64             `protobuf:"fixed64,2,opt,name=total_emissions,json=totalEmissions,proto3" json:"total_emissions,omitempty"`
+	Rows           []*MeasureRowResult `protobuf:"bytes,3,rep,name=rows,proto3" json:"rows,omitempty"`
+	Warnings       []string            `protobuf:"bytes,4,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// error is set, and the other fields are empty, when a MeasureStream batch
+	// fails. Measure reports failures as the call status instead.
+	Error *MeasureError `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *MeasureResponse) Reset() {
+	*x = MeasureResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_emissions_v1_emissions_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MeasureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeasureResponse) ProtoMessage() {}
+
+func (x *MeasureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_emissions_v1_emissions_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeasureResponse.ProtoReflect.Descriptor instead.
+func (*MeasureResponse) Descriptor() ([]byte, []int) {
+	return file_api_emissions_v1_emissions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MeasureResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *MeasureResponse) GetTotalEmissions() float64 {
+	if x != nil {
+		return x.TotalEmissions
+	}
+	return 0
+}
+
+func (x *MeasureResponse) GetRows() []*MeasureRowResult {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *MeasureResponse) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *MeasureResponse) GetError() *MeasureError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type MeasureError struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// code is a gRPC status code.
+	Code    int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *MeasureError) Reset() {
+	*x = MeasureError{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_emissions_v1_emissions_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MeasureError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeasureError) ProtoMessage() {}
+
+func (x *MeasureError) ProtoReflect() protoreflect.Message {
+	mi := &file_api_emissions_v1_emissions_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeasureError.ProtoReflect.Descriptor instead.
+func (*MeasureError) Descriptor() ([]byte, []int) {
+	return file_api_emissions_v1_emissions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MeasureError) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *MeasureError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_api_emissions_v1_emissions_proto protoreflect.FileDescriptor
+
+var file_api_emissions_v1_emissions_proto_rawDesc = []byte{
+	0x0a, 0x20, 0x61, 0x70, 0x69, 0x2f, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2f,
+	0x76, 0x31, 0x2f, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x0c, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x22, 0xc9, 0x01, 0x0a, 0x0a, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x6f, 0x77, 0x12,
+	0x18, 0x0a, 0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e,
+	0x6e, 0x65, 0x6c, 0x12, 0x20, 0x0a, 0x0b, 0x69, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x69, 0x6d, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x6e, 0x76, 0x65, 0x6e, 0x74, 0x6f,
+	0x72, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x69, 0x6e, 0x76,
+	0x65, 0x6e, 0x74, 0x6f, 0x72, 0x79, 0x49, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x75, 0x74, 0x63, 0x5f,
+	0x64, 0x61, 0x74, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x75, 0x74, 0x63, 0x44, 0x61, 0x74, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x69,
+	0x73, 0x5f, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0a, 0x69, 0x73, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x3e, 0x0a, 0x0e,
+	0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c,
+	0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x65,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x65, 0x61, 0x73,
+	0x75, 0x72, 0x65, 0x52, 0x6f, 0x77, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x22, 0xc8, 0x01, 0x0a,
+	0x10, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x6f, 0x77, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79,
+	0x49, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x5f, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x70, 0x72, 0x6f, 0x70, 0x65,
+	0x72, 0x74, 0x79, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x6f, 0x74, 0x61, 0x6c,
+	0x5f, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x0e, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x45, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73,
+	0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x06, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64, 0x12, 0x2d, 0x0a, 0x12, 0x69, 0x6e, 0x76, 0x65,
+	0x6e, 0x74, 0x6f, 0x72, 0x79, 0x5f, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x69, 0x6e, 0x76, 0x65, 0x6e, 0x74, 0x6f, 0x72, 0x79, 0x43,
+	0x6f, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x22, 0xdb, 0x01, 0x0a, 0x0f, 0x4d, 0x65, 0x61, 0x73,
+	0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x64, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x6f,
+	0x74, 0x61, 0x6c, 0x5f, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x0e, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x45, 0x6d, 0x69, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x73, 0x12, 0x32, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x6f, 0x77, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69,
+	0x6e, 0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69,
+	0x6e, 0x67, 0x73, 0x12, 0x30, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x3c, 0x0a, 0x0c, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x32, 0xac, 0x01, 0x0a, 0x10, 0x45, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x46, 0x0a, 0x07, 0x4d, 0x65, 0x61, 0x73,
+	0x75, 0x72, 0x65, 0x12, 0x1c, 0x2e, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1d, 0x2e, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x50, 0x0a, 0x0d, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x12, 0x1c, 0x2e, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31,
+	0x2e, 0x4d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1d, 0x2e, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x4d,
+	0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01,
+	0x30, 0x01, 0x42, 0x36, 0x5a, 0x34, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2d,
+	0x63, 0x61, 0x63, 0x68, 0x65, 0x2d, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x61, 0x70,
+	0x69, 0x2f, 0x65, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2f, 0x76, 0x31, 0x3b, 0x65,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_api_emissions_v1_emissions_proto_rawDescOnce sync.Once
+	file_api_emissions_v1_emissions_proto_rawDescData = file_api_emissions_v1_emissions_proto_rawDesc
+)
+
+func file_api_emissions_v1_emissions_proto_rawDescGZIP() []byte {
+	file_api_emissions_v1_emissions_proto_rawDescOnce.Do(func() {
+		file_api_emissions_v1_emissions_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_emissions_v1_emissions_proto_rawDescData)
+	})
+	return file_api_emissions_v1_emissions_proto_rawDescData
+}
+
+var file_api_emissions_v1_emissions_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_api_emissions_v1_emissions_proto_goTypes = []any{
+	(*MeasureRow)(nil),       // 0: emissions.v1.MeasureRow
+	(*MeasureRequest)(nil),   // 1: emissions.v1.MeasureRequest
+	(*MeasureRowResult)(nil), // 2: emissions.v1.MeasureRowResult
+	(*MeasureResponse)(nil),  // 3: emissions.v1.MeasureResponse
+	(*MeasureError)(nil),     // 4: emissions.v1.MeasureError
+}
+var file_api_emissions_v1_emissions_proto_depIdxs = []int32{
+	0, // 0: emissions.v1.MeasureRequest.rows:type_name -> emissions.v1.MeasureRow
+	2, // 1: emissions.v1.MeasureResponse.rows:type_name -> emissions.v1.MeasureRowResult
+	4, // 2: emissions.v1.MeasureResponse.error:type_name -> emissions.v1.MeasureError
+	1, // 3: emissions.v1.EmissionsService.Measure:input_type -> emissions.v1.MeasureRequest
+	1, // 4: emissions.v1.EmissionsService.MeasureStream:input_type -> emissions.v1.MeasureRequest
+	3, // 5: emissions.v1.EmissionsService.Measure:output_type -> emissions.v1.MeasureResponse
+	3, // 6: emissions.v1.EmissionsService.MeasureStream:output_type -> emissions.v1.MeasureResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_api_emissions_v1_emissions_proto_init() }
+func file_api_emissions_v1_emissions_proto_init() {
+	if File_api_emissions_v1_emissions_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_emissions_v1_emissions_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*MeasureRow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_emissions_v1_emissions_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*MeasureRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_emissions_v1_emissions_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*MeasureRowResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_emissions_v1_emissions_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*MeasureResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_emissions_v1_emissions_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*MeasureError); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_api_emissions_v1_emissions_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_emissions_v1_emissions_proto_goTypes,
+		DependencyIndexes: file_api_emissions_v1_emissions_proto_depIdxs,
+		MessageInfos:      file_api_emissions_v1_emissions_proto_msgTypes,
+	}.Build()
+	File_api_emissions_v1_emissions_proto = out.File
+	file_api_emissions_v1_emissions_proto_rawDesc = nil
+	file_api_emissions_v1_emissions_proto_goTypes = nil
+	file_api_emissions_v1_emissions_proto_depIdxs = nil
+}
@@ -0,0 +1,195 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.27.2
+// source: api/emissions/v1/emissions.proto
+
+package emissionsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	EmissionsService_Measure_FullMethodName       = "/emissions.v1.EmissionsService/Measure"
+	EmissionsService_MeasureStream_FullMethodName = "/emissions.v1.EmissionsService/MeasureStream"
+)
+
+// EmissionsServiceClient is the client API for EmissionsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// EmissionsService measures advertising emissions, serving cached results
+// where possible. It mirrors POST /v1/emissions/measure.
+type EmissionsServiceClient interface {
+	// Measure resolves a batch of rows.
+	Measure(ctx context.Context, in *MeasureRequest, opts ...grpc.CallOption) (*MeasureResponse, error)
+	// MeasureStream resolves each request message as a batch and answers it
+	// with one response message, in order. Failures of a single batch are
+	// reported in its response and do not end the stream.
+	MeasureStream(ctx context.Context, opts ...grpc.CallOption) (EmissionsService_MeasureStreamClient, error)
+}
+
+type emissionsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEmissionsServiceClient(cc grpc.ClientConnInterface) EmissionsServiceClient {
+	return &emissionsServiceClient{cc}
+}
+
+func (c *emissionsServiceClient) Measure(ctx context.Context, in *MeasureRequest, opts ...grpc.CallOption) (*MeasureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MeasureResponse)
+	err := c.cc.Invoke(ctx, EmissionsService_Measure_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emissionsServiceClient) MeasureStream(ctx context.Context, opts ...grpc.CallOption) (EmissionsService_MeasureStreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EmissionsService_ServiceDesc.Streams[0], EmissionsService_MeasureStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &emissionsServiceMeasureStreamClient{ClientStream: stream}
+	return x, nil
+}
+
+type EmissionsService_MeasureStreamClient interface {
+	Send(*MeasureRequest) error
+	Recv() (*MeasureResponse, error)
+	grpc.ClientStream
+}
+
+type emissionsServiceMeasureStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *emissionsServiceMeasureStreamClient) Send(m *MeasureRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *emissionsServiceMeasureStreamClient) Recv() (*MeasureResponse, error) {
+	m := new(MeasureResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EmissionsServiceServer is the server API for EmissionsService service.
+// All implementations must embed UnimplementedEmissionsServiceServer
+// for forward compatibility
+//
+// EmissionsService measures advertising emissions, serving cached results
+// where possible. It mirrors POST /v1/emissions/measure.
+type EmissionsServiceServer interface {
+	// Measure resolves a batch of rows.
+	Measure(context.Context, *MeasureRequest) (*MeasureResponse, error)
+	// MeasureStream resolves each request message as a batch and answers it
+	// with one response message, in order. Failures of a single batch are
+	// reported in its response and do not end the stream.
+	MeasureStream(EmissionsService_MeasureStreamServer) error
+	mustEmbedUnimplementedEmissionsServiceServer()
+}
+
+// UnimplementedEmissionsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedEmissionsServiceServer struct {
+}
+
+func (UnimplementedEmissionsServiceServer) Measure(context.Context, *MeasureRequest) (*MeasureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Measure not implemented")
+}
+func (UnimplementedEmissionsServiceServer) MeasureStream(EmissionsService_MeasureStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method MeasureStream not implemented")
+}
+func (UnimplementedEmissionsServiceServer) mustEmbedUnimplementedEmissionsServiceServer() {}
+
+// UnsafeEmissionsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EmissionsServiceServer will
+// result in compilation errors.
+type UnsafeEmissionsServiceServer interface {
+	mustEmbedUnimplementedEmissionsServiceServer()
+}
+
+func RegisterEmissionsServiceServer(s grpc.ServiceRegistrar, srv EmissionsServiceServer) {
+	s.RegisterService(&EmissionsService_ServiceDesc, srv)
+}
+
+func _EmissionsService_Measure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MeasureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmissionsServiceServer).Measure(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmissionsService_Measure_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmissionsServiceServer).Measure(ctx, req.(*MeasureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmissionsService_MeasureStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EmissionsServiceServer).MeasureStream(&emissionsServiceMeasureStreamServer{ServerStream: stream})
+}
+
+type EmissionsService_MeasureStreamServer interface {
+	Send(*MeasureResponse) error
+	Recv() (*MeasureRequest, error)
+	grpc.ServerStream
+}
+
+type emissionsServiceMeasureStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *emissionsServiceMeasureStreamServer) Send(m *MeasureResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *emissionsServiceMeasureStreamServer) Recv() (*MeasureRequest, error) {
+	m := new(MeasureRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EmissionsService_ServiceDesc is the grpc.ServiceDesc for EmissionsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EmissionsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "emissions.v1.EmissionsService",
+	HandlerType: (*EmissionsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Measure",
+			Handler:    _EmissionsService_Measure_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "MeasureStream",
+			Handler:       _EmissionsService_MeasureStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/emissions/v1/emissions.proto",
+}
@@ -0,0 +1,28 @@
+// Package openapi embeds the OpenAPI 3 description of the HTTP API. The
+// document is maintained by hand alongside the routes in server.NewHTTPServer;
+// the server validates requests against it and the server tests validate
+// responses, so the two cannot drift apart unnoticed.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Load parses and validates the embedded document.
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
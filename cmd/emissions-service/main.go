@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,11 +10,20 @@ import (
 	"syscall"
 	"time"
 
+	"emissions-cache-service/internal/audit"
+	"emissions-cache-service/internal/auth"
 	"emissions-cache-service/internal/client/scope3"
+	"emissions-cache-service/internal/idempotency"
+	"emissions-cache-service/internal/jobs"
+	"emissions-cache-service/internal/policy"
+	"emissions-cache-service/internal/ratelimit"
 	"emissions-cache-service/internal/repository/cache"
 	"emissions-cache-service/internal/server"
 	"emissions-cache-service/internal/service"
+	"emissions-cache-service/internal/slo"
+	"emissions-cache-service/internal/webhook"
 	"emissions-cache-service/pkg/config"
+	"emissions-cache-service/pkg/redact"
 )
 
 func main() {
@@ -23,6 +33,13 @@ func main() {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
+	// Keep credentials out of logs.
+	redact.AddSecret(cfg.Scope3.Token)
+	for _, k := range cfg.Auth.APIKeys {
+		redact.AddSecret(k.KeyHash)
+	}
+	log.SetOutput(redact.NewWriter(os.Stderr))
+
 	// Create top-level context for graceful shutdown.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -36,29 +53,301 @@ func main() {
 	if err != nil {
 		log.Fatalf("Invalid cleanup interval: %v", err)
 	}
-	emissionsCache := cache.NewInMemoryCache(cacheTTL, cleanupInterval, 0)
+	tenantLimits := make(map[string]cache.TenantLimits, len(cfg.Tenants))
+	for _, t := range cfg.Tenants {
+		var ttl time.Duration
+		if t.DefaultTTL != "" {
+			if ttl, err = time.ParseDuration(t.DefaultTTL); err != nil {
+				log.Fatalf("Invalid cache TTL for tenant %s: %v", t.ID, err)
+			}
+		}
+		tenantLimits[t.ID] = cache.TenantLimits{DefaultTTL: ttl, MaxEntries: t.MaxEntries}
+	}
+	emissionsCache := cache.NewInMemoryCache(cacheTTL, cleanupInterval, 0, cache.WithTenantLimits(tenantLimits))
 
-	// Initialize the Scope3 client with customizable options.
+	// Initialize the Scope3 client with customizable options. The token is
+	// read per request so rotated secrets take effect without a restart.
+	tokenSource, err := newTokenSource(cfg)
+	if err != nil {
+		log.Fatalf("Invalid Scope3 token source: %v", err)
+	}
 	scope3Client := scope3.NewClient(
 		cfg.Scope3.APIURL,
 		cfg.Scope3.Token,
 		scope3.WithTimeout(5*time.Second),
+		scope3.WithTokenSource(tokenSource),
 	)
 
+	// Initialize the audit trail of cache mutations and admin actions.
+	var auditLog *audit.Logger
+	if cfg.Audit.Enabled {
+		sink, err := audit.NewFileSink(cfg.Audit.Path)
+		if err != nil {
+			log.Fatalf("Could not open audit log %s: %v", cfg.Audit.Path, err)
+		}
+		auditLog = audit.NewLogger(sink)
+		defer auditLog.Close()
+	}
+
 	// Initialize the measure service with caching and API client.
-	measureService := service.NewMeasureService(emissionsCache, scope3Client)
+	serviceOpts := []service.ServiceOption{service.WithAuditLogger(auditLog)}
+	if cfg.RateLimit.Enabled {
+		if cfg.RateLimit.RequestsPerSecond <= 0 || cfg.RateLimit.Burst <= 0 {
+			log.Fatalf("Invalid rate limit: requests_per_second and burst must be positive")
+		}
+		if cfg.RateLimit.UpstreamRowsPerMinute <= 0 || cfg.RateLimit.UpstreamBurst <= 0 {
+			log.Fatalf("Invalid upstream row budget: upstream_rows_per_minute and upstream_burst must be positive")
+		}
+		upstreamBudget := ratelimit.NewLimiter(cfg.RateLimit.UpstreamRowsPerMinute/60, cfg.RateLimit.UpstreamBurst)
+		serviceOpts = append(serviceOpts, service.WithUpstreamBudget(upstreamBudget))
+	}
+	var priorityPolicy *policy.PriorityPolicy
+	if cfg.Priority.Enabled {
+		mode := policy.Mode(cfg.Priority.Mode)
+		if mode != policy.ModeReject && mode != policy.ModeDowngrade {
+			log.Fatalf("Invalid priority mode %q: must be %q or %q", mode, policy.ModeReject, policy.ModeDowngrade)
+		}
+		clients := make(map[string]policy.Rule, len(cfg.Priority.Clients))
+		for _, c := range cfg.Priority.Clients {
+			clients[c.ClientID] = policy.Rule{MaxEntries: c.MaxEntries, AllowedInventoryIDs: c.AllowedInventoryIDs}
+		}
+		defaults := policy.Rule{MaxEntries: cfg.Priority.MaxEntries, AllowedInventoryIDs: cfg.Priority.AllowedInventoryIDs}
+		priorityPolicy = policy.NewPriorityPolicy(mode, defaults, clients, emissionsCache)
+		serviceOpts = append(serviceOpts, service.WithPriorityPolicy(priorityPolicy))
+	}
+	measureService := service.NewMeasureService(emissionsCache, scope3Client, serviceOpts...)
+
+	// Initialize latency SLO tracking for cached and upstream requests.
+	sloWindow, err := cfg.GetSLOWindow()
+	if err != nil {
+		log.Fatalf("Invalid SLO window: %v", err)
+	}
+	cachedThreshold, err := cfg.GetSLOCachedThreshold()
+	if err != nil {
+		log.Fatalf("Invalid cached SLO threshold: %v", err)
+	}
+	upstreamThreshold, err := cfg.GetSLOUpstreamThreshold()
+	if err != nil {
+		log.Fatalf("Invalid upstream SLO threshold: %v", err)
+	}
+	sloTracker := slo.NewTracker(sloWindow, map[slo.Path]slo.Objective{
+		slo.PathCached:   {Threshold: cachedThreshold, Target: cfg.SLO.CachedTarget},
+		slo.PathUpstream: {Threshold: upstreamThreshold, Target: cfg.SLO.UpstreamTarget},
+	})
 
 	// Create and configure the HTTP server.
-	srv := server.NewHTTPServer(measureService, cfg.Server.Host, cfg.Server.Port)
+	serverOpts := []server.ServerOption{
+		server.WithCache(emissionsCache),
+		server.WithSLOTracker(sloTracker),
+		server.WithAuditLogger(auditLog),
+		server.WithRequestLimits(cfg.Server.MaxBodyBytes, cfg.Server.MaxRows),
+		// Outside development, error responses omit the underlying error.
+		server.WithErrorDetails(cfg.IsDevelopment()),
+		server.WithStreamLimits(cfg.Server.Stream.MaxBodyBytes, cfg.Server.Stream.MaxRows, cfg.Server.Stream.ChunkSize),
+	}
+	if cfg.Server.ValidateRequests {
+		serverOpts = append(serverOpts, server.WithRequestValidation())
+	}
+	if priorityPolicy != nil {
+		serverOpts = append(serverOpts, server.WithPriorityUsage(priorityPolicy))
+	}
+	if cfg.Server.AccessLog.Enabled {
+		switch cfg.Server.AccessLog.Format {
+		case server.AccessLogFormatCommon, server.AccessLogFormatCombined, server.AccessLogFormatJSON:
+		default:
+			log.Fatalf("Invalid access log format %q: must be %q, %q or %q", cfg.Server.AccessLog.Format,
+				server.AccessLogFormatCommon, server.AccessLogFormatCombined, server.AccessLogFormatJSON)
+		}
+		serverOpts = append(serverOpts, server.WithAccessLog(server.AccessLogConfig{
+			Format:       cfg.Server.AccessLog.Format,
+			SampleRate:   cfg.Server.AccessLog.SampleRate,
+			ExcludePaths: cfg.Server.AccessLog.ExcludePaths,
+		}, redact.NewWriter(os.Stdout)))
+	}
+
+	if cfg.Server.CORS.Enabled {
+		maxAge, err := cfg.GetCORSMaxAge()
+		if err != nil {
+			log.Fatalf("Invalid CORS max age: %v", err)
+		}
+		serverOpts = append(serverOpts, server.WithCORS(server.CORSConfig{
+			AllowedOrigins:   cfg.Server.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.Server.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.Server.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.Server.CORS.ExposedHeaders,
+			AllowCredentials: cfg.Server.CORS.AllowCredentials,
+			MaxAge:           maxAge,
+		}))
+	}
+
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.NewLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+		serverOpts = append(serverOpts, server.WithRateLimit(limiter))
+	}
+
+	// Replay responses to submissions retried with the same Idempotency-Key.
+	if cfg.Idempotency.Enabled {
+		window, err := cfg.GetIdempotencyWindow()
+		if err != nil {
+			log.Fatalf("Invalid idempotency window: %v", err)
+		}
+		store := idempotency.NewStore(window, cfg.Idempotency.MaxEntries, idempotency.WithMaxBytes(cfg.Idempotency.MaxBytes))
+		serverOpts = append(serverOpts, server.WithIdempotency(store))
+	}
+
+	// Process batch measurement jobs in the background.
+	if cfg.Jobs.Enabled {
+		if !cfg.Auth.Enabled {
+			log.Fatalf("jobs.enabled requires auth.enabled, so jobs have an owner")
+		}
+		if cfg.Jobs.MaxQueuedRows > 0 && cfg.Jobs.MaxQueuedRows < cfg.Jobs.MaxRows {
+			log.Fatalf("jobs.max_queued_rows must be at least jobs.max_rows")
+		}
+		retention, err := cfg.GetJobRetention()
+		if err != nil {
+			log.Fatalf("Invalid job retention: %v", err)
+		}
+		jobOpts := []jobs.ManagerOption{
+			jobs.WithWorkers(cfg.Jobs.Workers),
+			jobs.WithChunkSize(cfg.Jobs.ChunkSize),
+			jobs.WithMaxQueued(cfg.Jobs.MaxQueued),
+			jobs.WithMaxQueuedRows(cfg.Jobs.MaxQueuedRows),
+			jobs.WithRetention(retention),
+		}
+		// Notify callback URLs with a signed summary of finished jobs.
+		if cfg.Webhooks.Enabled {
+			if cfg.Webhooks.Secret == "" {
+				log.Fatalf("webhooks.secret is required when webhooks are enabled")
+			}
+			backoff, err := cfg.GetWebhookBackoff()
+			if err != nil {
+				log.Fatalf("Invalid webhook initial backoff: %v", err)
+			}
+			timeout, err := cfg.GetWebhookTimeout()
+			if err != nil {
+				log.Fatalf("Invalid webhook timeout: %v", err)
+			}
+			webhookOpts := []webhook.NotifierOption{
+				webhook.WithMaxAttempts(cfg.Webhooks.MaxAttempts),
+				webhook.WithBackoff(backoff),
+				webhook.WithTimeout(timeout),
+				webhook.WithAllowedHosts(cfg.Webhooks.AllowedHosts),
+				webhook.WithLogSize(cfg.Webhooks.LogSize),
+			}
+			if cfg.Webhooks.AllowPrivate {
+				webhookOpts = append(webhookOpts, webhook.WithPrivateAddresses())
+			}
+			notifier := webhook.NewNotifier(cfg.Webhooks.Secret, webhookOpts...)
+			jobOpts = append(jobOpts, jobs.WithNotifier(notifier))
+			serverOpts = append(serverOpts, server.WithJobWebhooks(notifier))
+		}
+		jobManager := jobs.NewManager(measureService, jobOpts...)
+		go jobManager.Run(ctx)
+		serverOpts = append(serverOpts, server.WithJobs(jobManager, cfg.Jobs.MaxBodyBytes, cfg.Jobs.MaxRows))
+	}
+
+	// Serve HTTPS, reloading the certificate so it can be renewed live. With a
+	// client CA bundle, verified client certificates identify the caller.
+	var authenticators []auth.Authenticator
+	if cfg.Server.TLS.Enabled {
+		reloader, err := server.NewCertReloader(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			log.Fatalf("Invalid TLS certificate: %v", err)
+		}
+		reloadInterval, err := cfg.GetTLSReloadInterval()
+		if err != nil {
+			log.Fatalf("Invalid TLS reload interval: %v", err)
+		}
+		go reloader.Watch(ctx, reloadInterval)
+		tlsConfig, err := server.NewTLSConfig(reloader, cfg.Server.TLS.ClientCAFile, cfg.Server.TLS.ClientAuth)
+		if err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+		serverOpts = append(serverOpts, server.WithTLS(tlsConfig))
+		if cfg.Server.TLS.ClientCAFile != "" {
+			authenticators = append(authenticators, auth.NewClientCertAuthenticator(cfg.Server.TLS.ClientScopes))
+		}
+	}
+
+	// Require API keys or JWTs, reloading key material so it can be rotated live.
+	if cfg.Auth.Enabled {
+		keys := make([]auth.APIKey, 0, len(cfg.Auth.APIKeys))
+		for _, k := range cfg.Auth.APIKeys {
+			keys = append(keys, auth.APIKey{ClientID: k.ClientID, KeyHash: k.KeyHash, Scopes: k.Scopes, Tenant: k.Tenant, CallbackURL: k.CallbackURL})
+		}
+		keyStore, err := auth.NewAPIKeyStore(keys, cfg.Auth.APIKeysFile)
+		if err != nil {
+			log.Fatalf("Invalid API keys: %v", err)
+		}
+		reloadInterval, err := cfg.GetAuthReloadInterval()
+		if err != nil {
+			log.Fatalf("Invalid auth reload interval: %v", err)
+		}
+		go keyStore.Watch(ctx, reloadInterval)
+		authenticators = append(authenticators, keyStore)
+
+		if cfg.Auth.JWT.JWKS != "" {
+			jwks, err := auth.NewJWKS(cfg.Auth.JWT.JWKS)
+			if err != nil {
+				log.Fatalf("Invalid JWKS: %v", err)
+			}
+			refreshInterval, err := cfg.GetJWKSRefreshInterval()
+			if err != nil {
+				log.Fatalf("Invalid JWKS refresh interval: %v", err)
+			}
+			leeway, err := cfg.GetJWTLeeway()
+			if err != nil {
+				log.Fatalf("Invalid JWT leeway: %v", err)
+			}
+			go jwks.Watch(ctx, refreshInterval)
+			authenticators = append(authenticators,
+				auth.NewJWTAuthenticator(jwks, cfg.Auth.JWT.Issuer, cfg.Auth.JWT.Audience, leeway))
+		}
+	}
+	switch {
+	case cfg.Auth.Enabled:
+		serverOpts = append(serverOpts, server.WithAuthentication(cfg.Auth.ExemptPaths, authenticators...))
+	case len(authenticators) > 0:
+		// With auth disabled, client certificates identify callers that
+		// present one without turning certless requests away.
+		serverOpts = append(serverOpts, server.WithOptionalAuthentication(authenticators...))
+	}
+	srv := server.NewHTTPServer(measureService, cfg.Server.Host, cfg.Server.Port, serverOpts...)
 
 	// Start the HTTP server in a separate goroutine.
 	go func() {
-		log.Printf("Starting server on %s:%d", cfg.Server.Host, cfg.Server.Port)
+		log.Printf("Starting server on %s:%d (TLS: %t)", cfg.Server.Host, cfg.Server.Port, cfg.Server.TLS.Enabled)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Could not listen on %s:%d: %v", cfg.Server.Host, cfg.Server.Port, err)
 		}
 	}()
 
+	// Start the optional gRPC server, sharing authentication, rate limits and
+	// TLS with the HTTP server.
+	var grpcSrv *server.GRPCServer
+	if cfg.Server.GRPCPort != 0 {
+		grpcSrv = server.NewGRPCServer(measureService, cfg.Server.Host, cfg.Server.GRPCPort, serverOpts...)
+		go func() {
+			log.Printf("Starting gRPC server on %s (TLS: %t)", grpcSrv.Addr, cfg.Server.TLS.Enabled)
+			if err := grpcSrv.ListenAndServe(); err != nil {
+				log.Fatalf("Could not serve gRPC on %s: %v", grpcSrv.Addr, err)
+			}
+		}()
+	}
+
+	// Start the optional admin server for pprof, runtime diagnostics and
+	// Prometheus metrics.
+	var adminSrv *server.HTTPServer
+	if cfg.Server.AdminPort != 0 {
+		adminSrv = server.NewAdminServer(cfg.Server.AdminHost, cfg.Server.AdminPort, cfg.Redacted(), sloTracker)
+		go func() {
+			log.Printf("Starting admin server on %s:%d", cfg.Server.AdminHost, cfg.Server.AdminPort)
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Could not listen on %s:%d: %v", cfg.Server.AdminHost, cfg.Server.AdminPort, err)
+			}
+		}()
+	}
+
 	// Listen for termination signals for graceful shutdown.
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -68,9 +357,40 @@ func main() {
 	// Attempt graceful shutdown.
 	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 5*time.Second)
 	defer shutdownCancel()
+	if grpcSrv != nil {
+		if err := grpcSrv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("gRPC server forced to shutdown: %v", err)
+		}
+	}
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Admin server forced to shutdown: %v", err)
+		}
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
 	log.Println("Server gracefully stopped.")
 }
+
+// newTokenSource builds the Scope3 token source selected in the configuration.
+func newTokenSource(cfg *config.Config) (scope3.TokenSource, error) {
+	ts := cfg.Scope3.TokenSource
+	switch ts.Type {
+	case "static", "":
+		return scope3.StaticToken(cfg.Scope3.Token), nil
+	case "env":
+		return scope3.NewEnvTokenSource(ts.Env), nil
+	case "file":
+		return scope3.NewFileTokenSource(ts.File), nil
+	case "command":
+		ttl, err := cfg.GetTokenCacheTTL()
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache TTL: %w", err)
+		}
+		return scope3.NewCommandTokenSource(ts.Command, ttl)
+	default:
+		return nil, fmt.Errorf("unknown type %q", ts.Type)
+	}
+}
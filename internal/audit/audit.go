@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"emissions-cache-service/internal/requestctx"
+)
+
+// Action identifies what happened.
+type Action string
+
+const (
+	ActionCacheSet    Action = "cache.set"
+	ActionCacheDelete Action = "cache.delete"
+	ActionCacheFlush  Action = "cache.flush"
+	ActionAdminCall   Action = "admin.call"
+)
+
+// Source identifies why a cache entry was written or removed.
+type Source string
+
+const (
+	SourceUpstream Source = "upstream"
+	SourceRefresh  Source = "refresh"
+	SourceWarmup   Source = "warmup"
+	SourceAdmin    Source = "admin"
+)
+
+// Event is a single audit record.
+type Event struct {
+	Time      time.Time       `json:"time"`
+	Action    Action          `json:"action"`
+	Source    Source          `json:"source,omitempty"`
+	Actor     string          `json:"actor,omitempty"`
+	RequestID string          `json:"requestId,omitempty"`
+	Tenant    string          `json:"tenant,omitempty"`
+	Key       string          `json:"key,omitempty"`
+	Priority  bool            `json:"priority,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Count     int             `json:"count,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Path      string          `json:"path,omitempty"`
+	Status    int             `json:"status,omitempty"`
+}
+
+// Sink persists audit events. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(e Event) error
+	Close() error
+}
+
+// Logger stamps events with request metadata and forwards them to a Sink.
+// A nil *Logger discards every event, so callers need not check for it.
+type Logger struct {
+	sink Sink
+	now  func() time.Time
+}
+
+// NewLogger creates a Logger writing to sink.
+func NewLogger(sink Sink) *Logger {
+	return &Logger{sink: sink, now: time.Now}
+}
+
+// Record fills in the time, actor and request ID from ctx and writes the event.
+// Failures are logged rather than returned so auditing never fails a request.
+func (l *Logger) Record(ctx context.Context, e Event) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = l.now().UTC()
+	}
+	info := requestctx.FromContext(ctx)
+	if e.Actor == "" {
+		e.Actor = info.ClientID()
+	}
+	if e.RequestID == "" {
+		e.RequestID = info.RequestID()
+	}
+	if err := l.sink.Write(e); err != nil {
+		log.Printf("failed to write audit event %s: %v", e.Action, err)
+	}
+}
+
+// Close releases the underlying sink.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.sink.Close()
+}
+
+// FileSink appends events to a local file as JSON lines.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileSink opens path for appending, creating it if necessary.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Write appends one event as a single JSON line.
+func (s *FileSink) Write(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(e)
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,63 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"emissions-cache-service/internal/audit"
+	"emissions-cache-service/internal/requestctx"
+)
+
+func TestFileSinkAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	for i := 0; i < 2; i++ {
+		sink, err := audit.NewFileSink(path)
+		if err != nil {
+			t.Fatalf("NewFileSink() error = %v", err)
+		}
+		ctx, info := requestctx.New(context.Background(), "req-1")
+		info.SetClientID("client-a")
+
+		logger := audit.NewLogger(sink)
+		logger.Record(ctx, audit.Event{Action: audit.ActionCacheSet, Source: audit.SourceUpstream, Key: "k", Priority: true})
+		if err := logger.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var events []audit.Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 appended events, got %d", len(events))
+	}
+	e := events[0]
+	if e.Actor != "client-a" || e.RequestID != "req-1" || e.Key != "k" || !e.Priority || e.Time.IsZero() {
+		t.Errorf("Expected event stamped with request metadata, got %+v", e)
+	}
+}
+
+func TestNilLoggerDiscards(t *testing.T) {
+	var logger *audit.Logger
+	logger.Record(context.Background(), audit.Event{Action: audit.ActionCacheFlush})
+	if err := logger.Close(); err != nil {
+		t.Errorf("Expected nil logger Close to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"emissions-cache-service/pkg/redact"
+
+	"gopkg.in/yaml.v2"
+)
+
+// APIKeyHeader is the request header carrying an API key.
+const APIKeyHeader = "X-API-Key"
+
+// hashPrefix marks the hashing scheme of a stored key hash.
+const hashPrefix = "sha256:"
+
+// MethodAPIKey identifies callers authenticated with an API key.
+const MethodAPIKey = "api_key"
+
+// errUnknownAPIKey is returned for keys that are not registered.
+var errUnknownAPIKey = errors.New("unknown API key")
+
+// DefaultAPIKeyScopes are granted to API keys registered without scopes, which
+// keeps keys issued before scopes existed working for measurement.
+var DefaultAPIKeyScopes = []string{ScopeMeasure}
+
+// APIKey registers the hash of a key for a client.
+type APIKey struct {
+	ClientID    string   `yaml:"client_id"`
+	KeyHash     string   `yaml:"key_hash"`
+	Scopes      []string `yaml:"scopes"`
+	Tenant      string   `yaml:"tenant"`
+	CallbackURL string   `yaml:"callback_url"`
+}
+
+// keysFile is the on-disk format of an API keys file.
+type keysFile struct {
+	Keys []APIKey `yaml:"keys"`
+}
+
+// HashAPIKey returns the stored representation of a plaintext key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore authenticates requests against a set of hashed API keys. Keys
+// come from static configuration and, optionally, a file that is re-read when
+// it changes so keys can be added or revoked without a restart.
+type APIKeyStore struct {
+	static []APIKey
+	path   string
+
+	mu      sync.RWMutex
+	keys    map[string]Identity
+	modTime time.Time
+
+	// presented records hashes whose plaintext key has been registered with
+	// the redactor, so each key is registered once.
+	presented sync.Map
+}
+
+// NewAPIKeyStore creates a store from static keys and an optional keys file.
+func NewAPIKeyStore(static []APIKey, path string) (*APIKeyStore, error) {
+	s := &APIKeyStore{static: static, path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload rebuilds the key set from static keys and the keys file.
+func (s *APIKeyStore) Reload() error {
+	entries := append([]APIKey(nil), s.static...)
+	var modTime time.Time
+	if s.path != "" {
+		info, err := os.Stat(s.path)
+		if err != nil {
+			return fmt.Errorf("stat API keys file: %w", err)
+		}
+		b, err := os.ReadFile(s.path)
+		if err != nil {
+			return fmt.Errorf("read API keys file: %w", err)
+		}
+		var f keysFile
+		if err := yaml.Unmarshal(b, &f); err != nil {
+			return fmt.Errorf("parse API keys file: %w", err)
+		}
+		entries = append(entries, f.Keys...)
+		modTime = info.ModTime()
+	}
+
+	keys := make(map[string]Identity, len(entries))
+	for _, e := range entries {
+		if e.ClientID == "" {
+			return errors.New("API key entry is missing client_id")
+		}
+		if !strings.HasPrefix(e.KeyHash, hashPrefix) {
+			return fmt.Errorf("API key for client %s must be a %s hash", e.ClientID, strings.TrimSuffix(hashPrefix, ":"))
+		}
+		redact.AddSecret(e.KeyHash)
+		scopes := e.Scopes
+		if len(scopes) == 0 {
+			scopes = DefaultAPIKeyScopes
+		}
+		keys[strings.ToLower(e.KeyHash)] = Identity{ClientID: e.ClientID, Method: MethodAPIKey, Scopes: scopes, Tenant: e.Tenant, CallbackURL: e.CallbackURL}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.modTime = modTime
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch polls the keys file every interval and reloads it when it changes,
+// until ctx is cancelled. A file that fails to load keeps the previous keys.
+func (s *APIKeyStore) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(s.path)
+			if err != nil {
+				log.Printf("API keys file unavailable, keeping current keys: %v", err)
+				continue
+			}
+			s.mu.RLock()
+			unchanged := info.ModTime().Equal(s.modTime)
+			s.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				log.Printf("Failed to reload API keys, keeping current keys: %v", err)
+				continue
+			}
+			log.Printf("Reloaded API keys from %s", s.path)
+		}
+	}
+}
+
+// Authenticate resolves the client owning the key in the X-API-Key header.
+func (s *APIKeyStore) Authenticate(r *http.Request) (Identity, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return Identity{}, ErrNoCredentials
+	}
+	hash := HashAPIKey(key)
+	s.mu.RLock()
+	id, ok := s.keys[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return Identity{}, errUnknownAPIKey
+	}
+	// Plaintext keys are only known once presented; mask valid ones from then on.
+	if _, seen := s.presented.LoadOrStore(hash, struct{}{}); !seen {
+		redact.AddSecret(key)
+	}
+	return id, nil
+}
@@ -0,0 +1,110 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/pkg/redact"
+)
+
+func requestWithKey(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", nil)
+	if key != "" {
+		req.Header.Set(auth.APIKeyHeader, key)
+	}
+	return req
+}
+
+func writeKeysFile(t *testing.T, path, clientID, key string) {
+	t.Helper()
+	content := "keys:\n  - client_id: " + clientID + "\n    key_hash: \"" + auth.HashAPIKey(key) + "\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write keys file: %v", err)
+	}
+}
+
+func TestAPIKeyStoreAuthenticate(t *testing.T) {
+	store, err := auth.NewAPIKeyStore([]auth.APIKey{{ClientID: "dashboard", KeyHash: auth.HashAPIKey("secret-1")}}, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+
+	id, err := store.Authenticate(requestWithKey("secret-1"))
+	if err != nil {
+		t.Fatalf("Expected known key to authenticate, got %v", err)
+	}
+	if id.ClientID != "dashboard" || id.Method != auth.MethodAPIKey {
+		t.Errorf("Unexpected identity: %+v", id)
+	}
+
+	if _, err := store.Authenticate(requestWithKey("wrong")); err == nil || errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("Expected unknown key to be rejected, got %v", err)
+	}
+	if _, err := store.Authenticate(requestWithKey("")); !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials without a key, got %v", err)
+	}
+}
+
+func TestAPIKeyStoreRejectsPlaintextKeys(t *testing.T) {
+	if _, err := auth.NewAPIKeyStore([]auth.APIKey{{ClientID: "c", KeyHash: "plaintext"}}, ""); err == nil {
+		t.Error("Expected unhashed key to be rejected")
+	}
+}
+
+func TestAPIKeyStoreWatchReloadsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	writeKeysFile(t, path, "pipeline", "old-key")
+
+	store, err := auth.NewAPIKeyStore(nil, path)
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, 10*time.Millisecond)
+
+	// Rotate the key and make sure the change in modification time is visible.
+	writeKeysFile(t, path, "pipeline", "new-key")
+	future := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to touch keys file: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := store.Authenticate(requestWithKey("new-key")); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := store.Authenticate(requestWithKey("new-key")); err != nil {
+		t.Fatalf("Expected rotated key to be accepted, got %v", err)
+	}
+	if _, err := store.Authenticate(requestWithKey("old-key")); err == nil {
+		t.Error("Expected revoked key to be rejected")
+	}
+}
+
+func TestAPIKeyStoreRegistersKeysWithRedactor(t *testing.T) {
+	hash := auth.HashAPIKey("redacted-key-1")
+	store, err := auth.NewAPIKeyStore([]auth.APIKey{{ClientID: "dashboard", KeyHash: hash}}, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+	if _, err := store.Authenticate(requestWithKey("redacted-key-1")); err != nil {
+		t.Fatalf("Expected known key to authenticate, got %v", err)
+	}
+
+	got := redact.String("hash " + hash + " key redacted-key-1")
+	if strings.Contains(got, hash) || strings.Contains(got, "redacted-key-1") {
+		t.Errorf("Expected key material to be masked, got %q", got)
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// MethodClientCert identifies callers authenticated with a TLS client certificate.
+const MethodClientCert = "client_cert"
+
+// ClientCertAuthenticator identifies callers by the subject of the client
+// certificate verified during the TLS handshake.
+type ClientCertAuthenticator struct {
+	scopes []string
+}
+
+// NewClientCertAuthenticator creates an authenticator granting scopes to every
+// caller presenting a verified certificate. Without scopes, callers get
+// DefaultAPIKeyScopes.
+func NewClientCertAuthenticator(scopes []string) *ClientCertAuthenticator {
+	if len(scopes) == 0 {
+		scopes = DefaultAPIKeyScopes
+	}
+	return &ClientCertAuthenticator{scopes: scopes}
+}
+
+// Authenticate resolves the caller from the leaf of the verified certificate
+// chain, using its common name or, failing that, its full subject. The first
+// organization in the subject, if any, is the caller's tenant.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Identity{}, ErrNoCredentials
+	}
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	clientID := subject.CommonName
+	if clientID == "" {
+		clientID = subject.String()
+	}
+	var tenant string
+	if len(subject.Organization) > 0 {
+		tenant = subject.Organization[0]
+	}
+	return Identity{ClientID: clientID, Method: MethodClientCert, Scopes: a.scopes, Tenant: tenant}, nil
+}
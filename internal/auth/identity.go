@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request carries no
+// credentials it understands, so the next authenticator can be tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Scopes understood by the service.
+const (
+	// ScopeMeasure allows calling the measure endpoint.
+	ScopeMeasure = "emissions:measure"
+	// ScopePriority allows marking rows as priority, pinning them in the cache.
+	ScopePriority = "emissions:priority"
+	// ScopeCacheAdmin allows inspecting and managing the cache.
+	ScopeCacheAdmin = "cache:admin"
+)
+
+// Identity describes an authenticated caller.
+type Identity struct {
+	// ClientID uniquely identifies the caller for logging, auditing and quotas.
+	ClientID string
+	// Method records how the caller was authenticated, e.g. "api_key".
+	Method string
+	// Scopes lists the permissions granted to the caller.
+	Scopes []string
+	// Tenant is the customer the caller acts for; its cache entries are kept
+	// apart from other tenants'. Empty means the shared namespace.
+	Tenant string
+	// CallbackURL is notified when the caller's batch jobs finish, unless a
+	// job names its own.
+	CallbackURL string
+}
+
+// HasScope reports whether the caller was granted scope.
+func (id Identity) HasScope(scope string) bool {
+	return containsString(id.Scopes, scope)
+}
+
+// Authenticator resolves the caller of a request. It returns ErrNoCredentials
+// when the request does not carry its kind of credentials, and any other error
+// when credentials are present but invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Identity, error)
+}
+
+// contextKey is unexported to avoid collisions with other packages.
+type contextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity attached to ctx, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval bounds how often an unknown key ID can trigger a reload.
+const minRefreshInterval = time.Minute
+
+// jwk is a single JSON Web Key as found in a JWKS document.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS holds the public keys used to verify JWT signatures, loaded from a
+// local file or an http(s) URL.
+type JWKS struct {
+	source     string
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+}
+
+// NewJWKS loads the key set from source, a file path or http(s) URL.
+func NewJWKS(source string) (*JWKS, error) {
+	j := &JWKS{
+		source:     source,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := j.Refresh(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Refresh reloads the key set from its source. Keys that cannot be parsed are
+// skipped; it fails only when no usable signing key remains.
+func (j *JWKS) Refresh() error {
+	b, err := j.read()
+	if err != nil {
+		return fmt.Errorf("load JWKS: %w", err)
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("parse JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Identity providers publish keys of types this service cannot
+		// verify alongside usable ones; those are skipped, not fatal.
+		pub, err := k.publicKey()
+		if err != nil {
+			log.Printf("Skipping JWK %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.lastRefresh = time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+// read fetches the raw JWKS document.
+func (j *JWKS) read() ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+	resp, err := j.httpClient.Get(j.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Key returns the public key with the given ID, reloading the set once if the
+// ID is unknown so that newly rotated keys are picked up.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.lastRefresh) > minRefreshInterval
+	j.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if stale {
+		if err := j.Refresh(); err != nil {
+			log.Printf("Failed to refresh JWKS: %v", err)
+		}
+		j.mu.RLock()
+		key, ok = j.keys[kid]
+		j.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// Watch reloads the key set every interval until ctx is cancelled. A failed
+// reload keeps the previous keys.
+func (j *JWKS) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.Refresh(); err != nil {
+				log.Printf("Failed to refresh JWKS, keeping current keys: %v", err)
+			}
+		}
+	}
+}
+
+// publicKey converts an RSA or P-256 EC JWK into a Go public key.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// decodeBigInt decodes a base64url-encoded unsigned big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MethodJWT identifies callers authenticated with a bearer JWT.
+const MethodJWT = "jwt"
+
+// KeySource resolves JWT signing keys by key ID.
+type KeySource interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// JWTAuthenticator validates RS256 and ES256 bearer tokens.
+type JWTAuthenticator struct {
+	keys     KeySource
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewJWTAuthenticator creates an authenticator accepting tokens signed by keys.
+// Empty issuer or audience disables the corresponding check.
+func NewJWTAuthenticator(keys KeySource, issuer, audience string, leeway time.Duration) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   leeway,
+		now:      time.Now,
+	}
+}
+
+// jwtHeader is the JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims holds the registered, scope and tenant claims this service understands.
+type jwtClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	ClientID  string   `json:"client_id"`
+	Scope     string   `json:"scope"`
+	Scp       []string `json:"scp"`
+	Tenant    string   `json:"tenant"`
+}
+
+// audience accepts the aud claim as either a string or an array of strings.
+type audience []string
+
+// UnmarshalJSON decodes a single audience or a list of audiences.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Authenticate validates the bearer token in the Authorization header.
+func (j *JWTAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return Identity{}, ErrNoCredentials
+	}
+	claims, err := j.verify(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return Identity{}, err
+	}
+
+	clientID := claims.Subject
+	if clientID == "" {
+		clientID = claims.ClientID
+	}
+	scopes := claims.Scp
+	if claims.Scope != "" {
+		scopes = append(scopes, strings.Fields(claims.Scope)...)
+	}
+	return Identity{ClientID: clientID, Method: MethodJWT, Scopes: scopes, Tenant: claims.Tenant}, nil
+}
+
+// verify checks the signature and registered claims of a compact JWT.
+func (j *JWTAuthenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	key, err := j.keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	now := j.now()
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(j.leeway)) {
+		return nil, errors.New("token has expired")
+	}
+	if claims.NotBefore != nil && now.Add(j.leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if j.issuer != "" && claims.Issuer != j.issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if j.audience != "" && !containsString(claims.Audience, j.audience) {
+		return nil, errors.New("token is not intended for this audience")
+	}
+	return &claims, nil
+}
+
+// verifySignature checks sig over digest for the given algorithm, refusing
+// keys whose type does not match it.
+func verifySignature(alg string, key crypto.PublicKey, digest, sig []byte) error {
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed with a non-RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("ES256 token signed with a non-EC key")
+		}
+		if len(sig) != 64 {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token into v.
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
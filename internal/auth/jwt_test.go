@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/auth"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signToken builds a compact JWT signed with key under the given algorithm.
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signingInput + "." + b64(sig)
+}
+
+// writeJWKS publishes the public halves of the keys as a JWKS file.
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+	doc := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa-1", "use": "sig",
+				"n": b64(rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec-1", "crv": "P-256",
+				"x": b64(ecKey.X.FillBytes(make([]byte, 32))),
+				"y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	}
+	b, _ := json.Marshal(doc)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	jwks, err := auth.NewJWKS(writeJWKS(t, rsaKey, ecKey))
+	if err != nil {
+		t.Fatalf("NewJWKS() error = %v", err)
+	}
+	authenticator := auth.NewJWTAuthenticator(jwks, "https://issuer.example", "emissions-service", time.Second)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   "https://issuer.example",
+			"aud":   []string{"emissions-service"},
+			"sub":   "bidder",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "emissions:measure cache:admin",
+		}
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := valid()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "RS256", token: signToken(t, "RS256", "rsa-1", rsaKey, valid())},
+		{name: "ES256", token: signToken(t, "ES256", "ec-1", ecKey, valid())},
+		{name: "expired", token: signToken(t, "RS256", "rsa-1", rsaKey, with("exp", time.Now().Add(-time.Hour).Unix())), wantErr: true},
+		{name: "missing expiry", token: signToken(t, "RS256", "rsa-1", rsaKey, with("exp", nil)), wantErr: true},
+		{name: "wrong issuer", token: signToken(t, "RS256", "rsa-1", rsaKey, with("iss", "https://evil.example")), wantErr: true},
+		{name: "wrong audience", token: signToken(t, "RS256", "rsa-1", rsaKey, with("aud", "other")), wantErr: true},
+		{name: "unknown key", token: signToken(t, "RS256", "rsa-2", rsaKey, valid()), wantErr: true},
+		{name: "algorithm mismatch", token: signToken(t, "ES256", "rsa-1", ecKey, valid()), wantErr: true},
+		{name: "tampered", token: signToken(t, "RS256", "rsa-1", rsaKey, valid()) + "x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			id, err := authenticator.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if id.ClientID != "bidder" || id.Method != auth.MethodJWT {
+				t.Errorf("Unexpected identity: %+v", id)
+			}
+			if !id.HasScope(auth.ScopeMeasure) || !id.HasScope(auth.ScopeCacheAdmin) || id.HasScope(auth.ScopePriority) {
+				t.Errorf("Unexpected scopes: %v", id.Scopes)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorWithoutBearer(t *testing.T) {
+	authenticator := auth.NewJWTAuthenticator(nil, "", "", 0)
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", nil)
+	if _, err := authenticator.Authenticate(req); err != auth.ErrNoCredentials {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+}
+
+func TestJWKSSkipsUnsupportedKeys(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writeDoc := func(keys ...map[string]string) string {
+		b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatalf("Failed to write JWKS: %v", err)
+		}
+		return path
+	}
+	unsupported := []map[string]string{
+		{"kty": "EC", "kid": "p384", "crv": "P-384", "x": "AQ", "y": "AQ"},
+		{"kty": "OKP", "kid": "ed25519", "crv": "Ed25519", "x": "AQ"},
+		{"kty": "RSA", "kid": "huge-e", "n": "AQAB", "e": b64(new(big.Int).Lsh(big.NewInt(1), 40).Bytes())},
+	}
+	usable := map[string]string{
+		"kty": "EC", "kid": "ec-1", "crv": "P-256",
+		"x": b64(ecKey.X.FillBytes(make([]byte, 32))),
+		"y": b64(ecKey.Y.FillBytes(make([]byte, 32))),
+	}
+
+	jwks, err := auth.NewJWKS(writeDoc(append(unsupported, usable)...))
+	if err != nil {
+		t.Fatalf("Expected a mixed key set to load, got %v", err)
+	}
+	if _, err := jwks.Key("ec-1"); err != nil {
+		t.Errorf("Expected the usable key to be loaded, got %v", err)
+	}
+	if _, err := jwks.Key("p384"); err == nil {
+		t.Error("Expected the unsupported key to be skipped")
+	}
+
+	if _, err := auth.NewJWKS(writeDoc(unsupported...)); err == nil {
+		t.Error("Expected a key set without usable keys to fail")
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/pkg/redact"
 )
 
 // ClientOption defines a functional option for configuring the Scope3 client.
@@ -18,7 +22,7 @@ type ClientOption func(*Client)
 // Client represents a client for interacting with the Scope3 API.
 type Client struct {
 	baseURL    string
-	token      string
+	tokens     TokenSource
 	httpClient *http.Client
 	userAgent  string
 }
@@ -37,11 +41,19 @@ func WithUserAgent(userAgent string) ClientOption {
 	}
 }
 
+// WithTokenSource obtains the bearer token from src for every request instead
+// of using the fixed token given to NewClient.
+func WithTokenSource(src TokenSource) ClientOption {
+	return func(c *Client) {
+		c.tokens = src
+	}
+}
+
 // NewClient initializes a new Scope3 client with the given options.
 func NewClient(baseURL, token string, opts ...ClientOption) *Client {
 	c := &Client{
 		baseURL: baseURL,
-		token:   token,
+		tokens:  StaticToken(token),
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second, // Default timeout.
 		},
@@ -64,38 +76,120 @@ func (c *Client) GetEmissions(ctx context.Context, req MeasureRequest) (*Measure
 		return nil, errors.NewInternalError("failed to marshal request", err)
 	}
 
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to obtain Scope3 token", err)
+	}
+	resp, err := c.post(ctx, url, body, token)
+	if err != nil {
+		return nil, err
+	}
+	// A rejected token may have been rotated since it was read; retry once
+	// if the source now has a different one.
+	if resp.status == http.StatusUnauthorized {
+		fresh, err := c.tokens.Refresh(ctx, token)
+		if err != nil {
+			return nil, errors.NewInternalError("failed to refresh Scope3 token", err)
+		}
+		if fresh != token {
+			if resp, err = c.post(ctx, url, body, fresh); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if resp.status < 200 || resp.status > 299 {
+		return nil, statusError(resp)
+	}
+
+	var measureResp MeasureResponse
+	if err := json.Unmarshal(resp.body, &measureResp); err != nil {
+		return nil, errors.NewInternalError("failed to unmarshal response", err)
+	}
+
+	return &measureResp, nil
+}
+
+// response is a Scope3 response read in full.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// post sends body to url authenticated with token and returns the response.
+func (c *Client) post(ctx context.Context, url string, body []byte, token string) (*response, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, errors.NewInternalError("failed to create request", err)
 	}
 
-	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	httpReq.Header.Set("Authorization", "Bearer "+token)
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("User-Agent", c.userAgent)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		// Log detailed error context if needed.
+		if isTimeout(err) {
+			return nil, errors.NewUpstreamTimeoutError("Scope3 API request timed out", err)
+		}
 		return nil, errors.NewExternalError("failed to make request to Scope3 API", err)
 	}
 	defer resp.Body.Close()
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
+		if isTimeout(err) {
+			return nil, errors.NewUpstreamTimeoutError("Scope3 API response timed out", err)
+		}
 		return nil, errors.NewExternalError("failed to read response body", err)
 	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: responseBody}, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, errors.NewExternalError(
-			fmt.Sprintf("Scope3 API error (status: %d)", resp.StatusCode),
-			fmt.Errorf("response: %s", string(responseBody)),
-		)
+// statusError classifies an unsuccessful Scope3 response by its cause, so
+// callers can tell a request Scope3 will never accept from one worth
+// retrying.
+func statusError(resp *response) error {
+	cause := fmt.Errorf("response: %s", redact.Snippet(resp.body))
+	switch {
+	case resp.status == http.StatusUnauthorized || resp.status == http.StatusForbidden:
+		return errors.NewUpstreamUnauthorizedError(
+			fmt.Sprintf("Scope3 API rejected the service credentials (status: %d)", resp.status), cause)
+	case resp.status == http.StatusTooManyRequests:
+		return errors.NewUpstreamRateLimitedError(
+			"Scope3 API rate limit exceeded", retryAfter(resp.header), cause)
+	case resp.status == http.StatusRequestTimeout || resp.status == http.StatusGatewayTimeout:
+		return errors.NewUpstreamTimeoutError(
+			fmt.Sprintf("Scope3 API timed out (status: %d)", resp.status), cause)
+	case resp.status >= 400 && resp.status < 500:
+		return errors.NewUpstreamValidationError(
+			fmt.Sprintf("Scope3 API rejected the request (status: %d)", resp.status), cause)
+	default:
+		return errors.NewExternalError(fmt.Sprintf("Scope3 API error (status: %d)", resp.status), cause)
 	}
+}
 
-	var measureResp MeasureResponse
-	if err := json.Unmarshal(responseBody, &measureResp); err != nil {
-		return nil, errors.NewInternalError("failed to unmarshal response", err)
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date,
+// returning zero when it is absent or invalid.
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
 	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
 
-	return &measureResp, nil
+// isTimeout reports whether err came from a request deadline or client timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return stderrors.Is(err, context.DeadlineExceeded) || (stderrors.As(err, &netErr) && netErr.Timeout())
 }
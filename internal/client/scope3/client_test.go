@@ -3,6 +3,7 @@ package scope3_test
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"emissions-cache-service/internal/client/scope3"
+	"emissions-cache-service/internal/errors"
 )
 
 func TestGetEmissionsSuccess(t *testing.T) {
@@ -114,43 +116,76 @@ func TestGetEmissionsErrors(t *testing.T) {
 		name       string
 		statusCode int
 		response   string
-		wantErr    bool
+		retryAfter string
+		delay      time.Duration
+		wantType   errors.ErrorType
+		wantRetry  bool
+		wantWait   time.Duration
 	}{
 		{
 			name:       "internal server error",
 			statusCode: http.StatusInternalServerError,
 			response:   "Internal Server Error",
-			wantErr:    true,
+			wantType:   errors.ErrorTypeUpstreamUnavailable,
+			wantRetry:  true,
+		},
+		{
+			name:       "bad request",
+			statusCode: http.StatusBadRequest,
+			response:   `{"error":"unknown country"}`,
+			wantType:   errors.ErrorTypeUpstreamValidation,
 		},
 		{
 			name:       "rate limit exceeded",
 			statusCode: http.StatusTooManyRequests,
 			response:   "Rate limit exceeded",
-			wantErr:    true,
+			retryAfter: "7",
+			wantType:   errors.ErrorTypeUpstreamRateLimited,
+			wantRetry:  true,
+			wantWait:   7 * time.Second,
 		},
 		{
 			name:       "unauthorized",
 			statusCode: http.StatusUnauthorized,
 			response:   "Invalid token",
-			wantErr:    true,
+			wantType:   errors.ErrorTypeUpstreamUnauthorized,
+		},
+		{
+			name:       "gateway timeout",
+			statusCode: http.StatusGatewayTimeout,
+			response:   "Gateway Timeout",
+			wantType:   errors.ErrorTypeUpstreamTimeout,
+			wantRetry:  true,
 		},
 		{
 			name:       "malformed response",
 			statusCode: http.StatusOK,
 			response:   "{invalid json}",
-			wantErr:    true,
+			wantType:   errors.ErrorTypeInternal,
+		},
+		{
+			name:       "timeout",
+			statusCode: http.StatusOK,
+			response:   "{}",
+			delay:      200 * time.Millisecond,
+			wantType:   errors.ErrorTypeUpstreamTimeout,
+			wantRetry:  true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
 				w.WriteHeader(tt.statusCode)
 				w.Write([]byte(tt.response))
 			}))
 			defer ts.Close()
 
-			client := scope3.NewClient(ts.URL, "dummy-token")
+			client := scope3.NewClient(ts.URL, "dummy-token", scope3.WithTimeout(50*time.Millisecond))
 			ctx := context.Background()
 
 			req := scope3.MeasureRequest{
@@ -166,8 +201,15 @@ func TestGetEmissionsErrors(t *testing.T) {
 			}
 
 			_, err := client.GetEmissions(ctx, req)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("GetEmissions() error = %v, wantErr %v", err, tt.wantErr)
+			if !stderrors.Is(err, &errors.ServiceError{Type: tt.wantType}) {
+				t.Fatalf("GetEmissions() error = %v, want type %s", err, tt.wantType.Code())
+			}
+			svcErr, _ := errors.As(err)
+			if svcErr.IsRetryable() != tt.wantRetry {
+				t.Errorf("IsRetryable() = %v, want %v", svcErr.IsRetryable(), tt.wantRetry)
+			}
+			if svcErr.RetryAfter != tt.wantWait {
+				t.Errorf("RetryAfter = %v, want %v", svcErr.RetryAfter, tt.wantWait)
 			}
 		})
 	}
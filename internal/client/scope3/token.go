@@ -0,0 +1,191 @@
+package scope3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"emissions-cache-service/pkg/redact"
+)
+
+// TokenSource supplies the bearer token sent with each Scope3 request. The
+// sources below register every token they obtain with the redact package.
+type TokenSource interface {
+	// Token returns the current token, possibly from a cache.
+	Token(ctx context.Context) (string, error)
+	// Refresh bypasses any cache and returns a freshly obtained token. It is
+	// called when Scope3 rejects the token rejected.
+	Refresh(ctx context.Context, rejected string) (string, error)
+}
+
+// StaticToken is a token fixed at startup.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Refresh returns the token; a static token cannot change.
+func (t StaticToken) Refresh(ctx context.Context, _ string) (string, error) {
+	return t.Token(ctx)
+}
+
+// EnvTokenSource reads the token from an environment variable on every request.
+type EnvTokenSource struct {
+	name string
+}
+
+// NewEnvTokenSource creates a source reading the variable name.
+func NewEnvTokenSource(name string) *EnvTokenSource {
+	return &EnvTokenSource{name: name}
+}
+
+// Token returns the variable's current value.
+func (s *EnvTokenSource) Token(context.Context) (string, error) {
+	token := strings.TrimSpace(os.Getenv(s.name))
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s is empty", s.name)
+	}
+	redact.AddSecret(token)
+	return token, nil
+}
+
+// Refresh re-reads the variable.
+func (s *EnvTokenSource) Refresh(ctx context.Context, _ string) (string, error) {
+	return s.Token(ctx)
+}
+
+// FileTokenSource reads the token from a file, such as a mounted secret, and
+// re-reads it whenever the file changes so rotated tokens are picked up.
+type FileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileTokenSource creates a source reading path.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{path: path}
+}
+
+// Token returns the file's contents, re-reading it if it changed since the
+// last read.
+func (s *FileTokenSource) Token(context.Context) (string, error) {
+	return s.read(false)
+}
+
+// Refresh re-reads the file unconditionally.
+func (s *FileTokenSource) Refresh(context.Context, string) (string, error) {
+	return s.read(true)
+}
+
+// read returns the cached token unless the file changed or force is set.
+func (s *FileTokenSource) read(force bool) (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("stat token file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !force && s.token != "" && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.token, nil
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", s.path)
+	}
+	redact.AddSecret(token)
+	s.token, s.modTime, s.size = token, info.ModTime(), info.Size()
+	return token, nil
+}
+
+const (
+	// minCommandRefresh is how long a token fetched by a command is kept
+	// even if Scope3 rejects it, so a burst of 401s runs the command once.
+	minCommandRefresh = 10 * time.Second
+	// commandTimeout bounds a token command run.
+	commandTimeout = 30 * time.Second
+)
+
+// CommandTokenSource obtains the token from the standard output of a command,
+// such as a secrets manager CLI, caching it for a while between runs.
+type CommandTokenSource struct {
+	command []string
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	token     string
+	fetchedAt time.Time
+	now       func() time.Time
+}
+
+// NewCommandTokenSource creates a source running command, given as the program
+// followed by its arguments, at most once per ttl.
+func NewCommandTokenSource(command []string, ttl time.Duration) (*CommandTokenSource, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("token command is empty")
+	}
+	return &CommandTokenSource{command: command, ttl: ttl, now: time.Now}, nil
+}
+
+// Token returns the cached token, running the command if it is older than ttl.
+func (s *CommandTokenSource) Token(ctx context.Context) (string, error) {
+	return s.run(ctx, "")
+}
+
+// Refresh runs the command again, unless the cached token already differs
+// from rejected, e.g. because a concurrent request refreshed it, or was
+// fetched within minCommandRefresh.
+func (s *CommandTokenSource) Refresh(ctx context.Context, rejected string) (string, error) {
+	return s.run(ctx, rejected)
+}
+
+// run returns the cached token unless it expired or, when rejected is set,
+// is the rejected token and old enough to be refreshed. The command runs
+// under mu so concurrent callers share one run.
+func (s *CommandTokenSource) run(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		age := s.now().Sub(s.fetchedAt)
+		if rejected == "" && age < s.ttl {
+			return s.token, nil
+		}
+		if rejected != "" && (s.token != rejected || age < minCommandRefresh) {
+			return s.token, nil
+		}
+	}
+
+	// Other callers wait on this run, so it must not be cancelled along with
+	// the request that started it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commandTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("run token command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("token command produced no output")
+	}
+	redact.AddSecret(token)
+	s.token, s.fetchedAt = token, s.now()
+	return token, nil
+}
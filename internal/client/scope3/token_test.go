@@ -0,0 +1,132 @@
+package scope3_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/client/scope3"
+)
+
+func TestFileTokenSourceRereadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	src := scope3.NewFileTokenSource(path)
+
+	token, err := src.Token(context.Background())
+	if err != nil || token != "first" {
+		t.Fatalf("Token() = %q, %v; want %q", token, err, "first")
+	}
+
+	if err := os.WriteFile(path, []byte("second-token\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	token, err = src.Token(context.Background())
+	if err != nil || token != "second-token" {
+		t.Errorf("Token() after rotation = %q, %v; want %q", token, err, "second-token")
+	}
+}
+
+func TestCommandTokenSourceCaches(t *testing.T) {
+	src, err := scope3.NewCommandTokenSource([]string{"echo", "from-command"}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCommandTokenSource() error = %v", err)
+	}
+	token, err := src.Token(context.Background())
+	if err != nil || token != "from-command" {
+		t.Errorf("Token() = %q, %v; want %q", token, err, "from-command")
+	}
+
+	failing, _ := scope3.NewCommandTokenSource([]string{"false"}, time.Hour)
+	if _, err := failing.Token(context.Background()); err == nil {
+		t.Error("Expected failing command to return an error")
+	}
+}
+
+func TestCommandTokenSourceRefreshesOnce(t *testing.T) {
+	// The command prints how many times it has run.
+	count := filepath.Join(t.TempDir(), "runs")
+	src, err := scope3.NewCommandTokenSource([]string{"sh", "-c", "echo run >> " + count + " && wc -l < " + count}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCommandTokenSource() error = %v", err)
+	}
+
+	// A cancelled request must not fail the run other callers wait on.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	token, err := src.Token(ctx)
+	if err != nil || token != "1" {
+		t.Fatalf("Token() = %q, %v; want %q", token, err, "1")
+	}
+
+	tests := []struct {
+		name     string
+		rejected string
+	}{
+		{name: "already replaced", rejected: "0"},
+		{name: "fetched recently", rejected: "1"},
+	}
+	for _, tt := range tests {
+		token, err := src.Refresh(context.Background(), tt.rejected)
+		if err != nil || token != "1" {
+			t.Errorf("%s: Refresh() = %q, %v; want the cached token without running the command", tt.name, token, err)
+		}
+	}
+}
+
+// rotatingSource serves a stale token until refreshed.
+type rotatingSource struct {
+	refreshed bool
+}
+
+func (s *rotatingSource) Token(context.Context) (string, error) {
+	if s.refreshed {
+		return "fresh", nil
+	}
+	return "stale", nil
+}
+
+func (s *rotatingSource) Refresh(ctx context.Context, _ string) (string, error) {
+	s.refreshed = true
+	return s.Token(ctx)
+}
+
+func TestGetEmissionsRetriesWithFreshToken(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(scope3.MeasureResponse{RequestID: "req-1"})
+	}))
+	defer ts.Close()
+
+	client := scope3.NewClient(ts.URL, "", scope3.WithTokenSource(&rotatingSource{}))
+	resp, err := client.GetEmissions(context.Background(), scope3.MeasureRequest{})
+	if err != nil {
+		t.Fatalf("GetEmissions() error = %v", err)
+	}
+	if resp.RequestID != "req-1" || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected success after one retry, got %+v after %d calls", resp, calls)
+	}
+
+	// A static token cannot change, so a 401 is not retried.
+	atomic.StoreInt32(&calls, 0)
+	static := scope3.NewClient(ts.URL, "stale")
+	if _, err := static.GetEmissions(context.Background(), scope3.MeasureRequest{}); err == nil {
+		t.Error("Expected 401 with a static token to fail")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected a single call with a static token, got %d", n)
+	}
+}
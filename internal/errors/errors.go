@@ -1,8 +1,11 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // ErrorType represents different categories of errors.
@@ -11,14 +14,112 @@ type ErrorType int
 const (
 	ErrorTypeInternal ErrorType = iota
 	ErrorTypeValidation
+	// ErrorTypeExternal means Scope3 could not be reached or failed.
 	ErrorTypeExternal
+	ErrorTypeNotFound
+	ErrorTypeUnauthorized
+	ErrorTypeForbidden
+	ErrorTypeRateLimited
+	ErrorTypePayloadTooLarge
+	// ErrorTypeUpstreamTimeout means Scope3 did not answer in time.
+	ErrorTypeUpstreamTimeout
+	// ErrorTypeUpstreamUnauthorized means Scope3 rejected the service's own
+	// credentials; it is not the caller's fault.
+	ErrorTypeUpstreamUnauthorized
+	// ErrorTypeUpstreamValidation means Scope3 rejected the request built
+	// from the caller's rows as invalid; retrying it cannot succeed.
+	ErrorTypeUpstreamValidation
+	// ErrorTypeUpstreamRateLimited means Scope3 throttled the service.
+	ErrorTypeUpstreamRateLimited
+	// ErrorTypeIdempotencyKeyReused means an Idempotency-Key was sent again
+	// with a different request.
+	ErrorTypeIdempotencyKeyReused
+	// ErrorTypeIdempotencyKeyInUse means the first request sent with an
+	// Idempotency-Key has not finished yet.
+	ErrorTypeIdempotencyKeyInUse
 )
 
+// ErrorTypeUpstreamUnavailable names ErrorTypeExternal by the upstream cause
+// it describes.
+const ErrorTypeUpstreamUnavailable = ErrorTypeExternal
+
+// kind describes how an ErrorType is reported to callers.
+type kind struct {
+	code   string
+	title  string
+	status int
+	// public marks types whose message is safe to show to callers. Other
+	// types may describe internal state or upstream responses and are
+	// reported by title only.
+	public bool
+	// upstream marks failures of the Scope3 API.
+	upstream bool
+	// retryable marks transient failures a later attempt may not hit.
+	retryable bool
+}
+
+// catalogue maps each ErrorType to its stable code, title and HTTP status.
+// Codes are part of the API and must not change once published.
+var catalogue = map[ErrorType]kind{
+	ErrorTypeInternal:             {code: "INTERNAL_ERROR", title: "Internal server error", status: http.StatusInternalServerError},
+	ErrorTypeValidation:           {code: "VALIDATION_FAILED", title: "Validation failed", status: http.StatusBadRequest, public: true},
+	ErrorTypeExternal:             {code: "UPSTREAM_UNAVAILABLE", title: "External service error", status: http.StatusServiceUnavailable, upstream: true, retryable: true},
+	ErrorTypeNotFound:             {code: "NOT_FOUND", title: "Not found", status: http.StatusNotFound, public: true},
+	ErrorTypeUnauthorized:         {code: "UNAUTHORIZED", title: "Unauthorized", status: http.StatusUnauthorized, public: true},
+	ErrorTypeForbidden:            {code: "FORBIDDEN", title: "Forbidden", status: http.StatusForbidden, public: true},
+	ErrorTypeRateLimited:          {code: "RATE_LIMITED", title: "Rate limited", status: http.StatusTooManyRequests, public: true},
+	ErrorTypePayloadTooLarge:      {code: "PAYLOAD_TOO_LARGE", title: "Payload too large", status: http.StatusRequestEntityTooLarge, public: true},
+	ErrorTypeUpstreamTimeout:      {code: "UPSTREAM_TIMEOUT", title: "Upstream timeout", status: http.StatusGatewayTimeout, upstream: true, retryable: true},
+	ErrorTypeUpstreamUnauthorized: {code: "UPSTREAM_UNAUTHORIZED", title: "Upstream rejected the service credentials", status: http.StatusBadGateway, upstream: true},
+	ErrorTypeUpstreamValidation:   {code: "UPSTREAM_VALIDATION_FAILED", title: "Upstream rejected the request", status: http.StatusUnprocessableEntity, public: true, upstream: true},
+	ErrorTypeUpstreamRateLimited:  {code: "UPSTREAM_RATE_LIMITED", title: "Upstream rate limited", status: http.StatusTooManyRequests, upstream: true, retryable: true},
+	ErrorTypeIdempotencyKeyReused: {code: "IDEMPOTENCY_KEY_REUSED", title: "Idempotency key reused", status: http.StatusUnprocessableEntity, public: true},
+	ErrorTypeIdempotencyKeyInUse:  {code: "IDEMPOTENCY_KEY_IN_USE", title: "Idempotency key in use", status: http.StatusConflict, public: true},
+}
+
+// lookup returns the catalogue entry for t, treating unknown types as internal.
+func (t ErrorType) lookup() kind {
+	if k, ok := catalogue[t]; ok {
+		return k
+	}
+	return catalogue[ErrorTypeInternal]
+}
+
+// Code returns the stable machine-readable code for t, e.g. "VALIDATION_FAILED".
+func (t ErrorType) Code() string {
+	return t.lookup().code
+}
+
+// Title returns a short human-readable summary of t.
+func (t ErrorType) Title() string {
+	return t.lookup().title
+}
+
+// HTTPStatus returns the HTTP status code errors of type t are reported with.
+func (t ErrorType) HTTPStatus() int {
+	return t.lookup().status
+}
+
+// IsUpstream reports whether t describes a failure of the Scope3 API.
+func (t ErrorType) IsUpstream() bool {
+	return t.lookup().upstream
+}
+
+// FieldError describes a problem with one field of a request.
+type FieldError struct {
+	// Field locates the field, as a JSON pointer into the body such as
+	// "/rows/0/impressions", a parameter name, or a CSV line such as "line 3".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // ServiceError encapsulates error details for the service.
 type ServiceError struct {
-	Type    ErrorType // The category of the error.
-	Message string    // A human-readable error message.
-	Err     error     // The underlying error.
+	Type       ErrorType     // The category of the error.
+	Message    string        // A human-readable error message.
+	Err        error         // The underlying error.
+	RetryAfter time.Duration // How long the caller should wait before retrying, if known.
+	Fields     []FieldError  // Problems with individual request fields, if any.
 }
 
 // Error returns the formatted error string.
@@ -29,9 +130,34 @@ func (e *ServiceError) Error() string {
 	return e.Message
 }
 
-// IsRetryable determines if the error is retryable (typically for external errors).
+// Unwrap returns the underlying error, so errors.Is and errors.As see through
+// a ServiceError to its cause.
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether e has the same type as target, when target is a
+// *ServiceError without a message, so errors.Is(err,
+// &ServiceError{Type: ErrorTypeUpstreamTimeout}) matches any upstream timeout.
+func (e *ServiceError) Is(target error) bool {
+	t, ok := target.(*ServiceError)
+	return ok && t.Message == "" && t.Type == e.Type
+}
+
+// IsRetryable reports whether a later attempt may succeed: Scope3 being
+// unavailable, timing out or throttling the service. Errors caused by the
+// request itself or by credentials are not retryable.
 func (e *ServiceError) IsRetryable() bool {
-	return e.Type == ErrorTypeExternal
+	return e.Type.lookup().retryable
+}
+
+// As finds the first ServiceError in err's chain.
+func As(err error) (*ServiceError, bool) {
+	var svcErr *ServiceError
+	if stderrors.As(err, &svcErr) {
+		return svcErr, true
+	}
+	return nil, false
 }
 
 // NewInternalError creates a new internal error.
@@ -51,6 +177,58 @@ func NewValidationError(message string) *ServiceError {
 	}
 }
 
+// NewFieldValidationError creates a new validation error listing the fields
+// at fault.
+func NewFieldValidationError(message string, fields ...FieldError) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeValidation,
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+// NewNotFoundError creates a new error for a missing resource.
+func NewNotFoundError(message string) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeNotFound,
+		Message: message,
+	}
+}
+
+// NewUnauthorizedError creates a new error for a caller that failed authentication.
+func NewUnauthorizedError(message string) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeUnauthorized,
+		Message: message,
+	}
+}
+
+// NewForbiddenError creates a new error for a caller lacking permission.
+func NewForbiddenError(message string) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeForbidden,
+		Message: message,
+	}
+}
+
+// NewRateLimitedError creates a new error for a caller that exceeded a rate
+// limit or budget. retryAfter may be zero when no retry can succeed.
+func NewRateLimitedError(message string, retryAfter time.Duration) *ServiceError {
+	return &ServiceError{
+		Type:       ErrorTypeRateLimited,
+		Message:    message,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewPayloadTooLargeError creates a new error for a request exceeding a size limit.
+func NewPayloadTooLargeError(message string) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypePayloadTooLarge,
+		Message: message,
+	}
+}
+
 // NewExternalError creates a new error related to external systems.
 func NewExternalError(message string, err error) *ServiceError {
 	return &ServiceError{
@@ -60,18 +238,116 @@ func NewExternalError(message string, err error) *ServiceError {
 	}
 }
 
-// ToHTTPError maps a ServiceError to an HTTP status code and a message.
-// This can be extended for structured logging or external error reporting.
+// NewUpstreamTimeoutError creates a new error for a Scope3 call that timed out.
+func NewUpstreamTimeoutError(message string, err error) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeUpstreamTimeout,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// NewUpstreamUnauthorizedError creates a new error for Scope3 rejecting the
+// service's credentials.
+func NewUpstreamUnauthorizedError(message string, err error) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeUpstreamUnauthorized,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// NewUpstreamValidationError creates a new error for Scope3 rejecting a
+// request as invalid.
+func NewUpstreamValidationError(message string, err error) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeUpstreamValidation,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// NewUpstreamRateLimitedError creates a new error for Scope3 throttling the
+// service. retryAfter is zero when Scope3 did not say how long to wait.
+func NewUpstreamRateLimitedError(message string, retryAfter time.Duration, err error) *ServiceError {
+	return &ServiceError{
+		Type:       ErrorTypeUpstreamRateLimited,
+		Message:    message,
+		Err:        err,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewIdempotencyKeyReusedError creates a new error for an Idempotency-Key
+// sent with a different request than the one it was first used for.
+func NewIdempotencyKeyReusedError(message string) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeIdempotencyKeyReused,
+		Message: message,
+	}
+}
+
+// NewIdempotencyKeyInUseError creates a new error for a request repeating one
+// that is still being processed.
+func NewIdempotencyKeyInUseError(message string) *ServiceError {
+	return &ServiceError{
+		Type:    ErrorTypeIdempotencyKeyInUse,
+		Message: message,
+	}
+}
+
+// ToHTTPError maps an error to an HTTP status code and the message shown to
+// callers. Messages of errors that may reveal internal state are replaced by
+// the title of their type.
 func ToHTTPError(err error) (int, string) {
-	if svcErr, ok := err.(*ServiceError); ok {
-		switch svcErr.Type {
-		case ErrorTypeValidation:
-			return http.StatusBadRequest, svcErr.Message
-		case ErrorTypeExternal:
-			return http.StatusServiceUnavailable, "External service error"
-		default:
-			return http.StatusInternalServerError, "Internal server error"
-		}
-	}
-	return http.StatusInternalServerError, "Unknown error"
+	svcErr, ok := As(err)
+	if !ok {
+		return http.StatusInternalServerError, "Unknown error"
+	}
+	k := svcErr.Type.lookup()
+	if k.public {
+		return k.status, svcErr.Message
+	}
+	return k.status, k.title
+}
+
+// ProblemContentType is the media type of problem details responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object describing a failed request.
+type Problem struct {
+	// Type identifies the kind of problem; it is derived from Code.
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail explains this occurrence. It is omitted for errors whose
+	// message may reveal internal state.
+	Detail string `json:"detail,omitempty"`
+	// Code is a stable, machine-readable identifier such as "RATE_LIMITED".
+	Code      string       `json:"code"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Cause is the redacted underlying error, set in development only.
+	Cause string `json:"cause,omitempty"`
+}
+
+// ToProblem describes err as problem details. Errors that are not
+// ServiceErrors are reported as internal errors.
+func ToProblem(err error) *Problem {
+	svcErr, ok := As(err)
+	if !ok {
+		svcErr = &ServiceError{Type: ErrorTypeInternal}
+	}
+	k := svcErr.Type.lookup()
+	p := &Problem{
+		Type:   "urn:emissions-cache:problem:" + strings.ToLower(strings.ReplaceAll(k.code, "_", "-")),
+		Title:  k.title,
+		Status: k.status,
+		Code:   k.code,
+		Errors: svcErr.Fields,
+	}
+	if k.public {
+		p.Detail = svcErr.Message
+	}
+	return p
 }
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"emissions-cache-service/internal/audit"
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/repository/cache"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultTopKeys is the number of hottest keys reported when none is requested.
+const defaultTopKeys = 10
+
+// CacheManager exposes cache statistics and administrative operations, either
+// cache-wide or for a single tenant.
+type CacheManager interface {
+	Stats(topN int) cache.Stats
+	TenantStats(tenant string, topN int) cache.Stats
+	Delete(key string) bool
+	DeleteForTenant(tenant, key string) bool
+	Flush() int
+	FlushTenant(tenant string) int
+}
+
+// CacheHandler handles HTTP requests for cache introspection and management.
+type CacheHandler struct {
+	cache    CacheManager
+	auditLog *audit.Logger
+}
+
+// NewCacheHandler creates a new CacheHandler. auditLog may be nil.
+func NewCacheHandler(c CacheManager, auditLog *audit.Logger) *CacheHandler {
+	return &CacheHandler{cache: c, auditLog: auditLog}
+}
+
+// scopedTenant returns the tenant an admin request applies to. Callers bound
+// to a tenant are confined to it and may not name another in the "tenant"
+// query parameter; callers without one act cache-wide unless they name a
+// tenant.
+func scopedTenant(r *http.Request) (string, error) {
+	requested := r.URL.Query().Get("tenant")
+	id, _ := auth.FromContext(r.Context())
+	if id.Tenant == "" {
+		return requested, nil
+	}
+	if requested != "" && requested != id.Tenant {
+		return "", errors.NewForbiddenError("cannot manage the cache of another tenant")
+	}
+	return id.Tenant, nil
+}
+
+// Stats handles the cache statistics endpoint.
+// The optional "top" query parameter controls how many hot keys are returned
+// and the optional "tenant" parameter restricts the statistics to one tenant.
+// Callers bound to a tenant only see that tenant's statistics.
+func (h *CacheHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	topN := defaultTopKeys
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, errors.NewValidationError("top must be a non-negative integer"))
+			return
+		}
+		topN = n
+	}
+
+	tenant, err := scopedTenant(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	if tenant != "" {
+		respondWithJSON(w, h.cache.TenantStats(tenant, topN))
+		return
+	}
+	respondWithJSON(w, h.cache.Stats(topN))
+}
+
+// Delete handles removal of a single cache entry. Callers bound to a tenant
+// can only remove that tenant's entries.
+func (h *CacheHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	var deleted bool
+	if id, _ := auth.FromContext(r.Context()); id.Tenant != "" {
+		deleted = h.cache.DeleteForTenant(id.Tenant, key)
+	} else {
+		deleted = h.cache.Delete(key)
+	}
+	if !deleted {
+		respondWithError(w, errors.NewNotFoundError("cache entry not found"))
+		return
+	}
+	h.auditLog.Record(r.Context(), audit.Event{
+		Action: audit.ActionCacheDelete,
+		Source: audit.SourceAdmin,
+		Key:    key,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Flush handles removal of every cache entry, or only those of the tenant
+// given by the optional "tenant" query parameter. Callers bound to a tenant
+// only flush that tenant's entries.
+func (h *CacheHandler) Flush(w http.ResponseWriter, r *http.Request) {
+	tenant, err := scopedTenant(r)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	var removed int
+	if tenant != "" {
+		removed = h.cache.FlushTenant(tenant)
+	} else {
+		removed = h.cache.Flush()
+	}
+	h.auditLog.Record(r.Context(), audit.Event{
+		Action: audit.ActionCacheFlush,
+		Source: audit.SourceAdmin,
+		Tenant: tenant,
+		Count:  removed,
+	})
+	respondWithJSON(w, map[string]int{"removed": removed})
+}
@@ -0,0 +1,213 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/internal/audit"
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/repository/cache"
+
+	"github.com/gorilla/mux"
+)
+
+type dummyCacheManager struct {
+	topN    int
+	tenant  string
+	entries map[string]bool
+}
+
+func (d *dummyCacheManager) Stats(topN int) cache.Stats {
+	d.topN = topN
+	return cache.Stats{Entries: 2, PriorityEntries: 1, StandardEntries: 1}
+}
+
+func (d *dummyCacheManager) TenantStats(tenant string, topN int) cache.Stats {
+	d.tenant = tenant
+	d.topN = topN
+	return cache.Stats{Entries: 1, StandardEntries: 1}
+}
+
+func (d *dummyCacheManager) Delete(key string) bool {
+	if !d.entries[key] {
+		return false
+	}
+	delete(d.entries, key)
+	return true
+}
+
+func (d *dummyCacheManager) DeleteForTenant(tenant, key string) bool {
+	if !strings.HasPrefix(key, tenant+"/") {
+		return false
+	}
+	return d.Delete(key)
+}
+
+func (d *dummyCacheManager) Flush() int {
+	n := len(d.entries)
+	d.entries = map[string]bool{}
+	return n
+}
+
+func (d *dummyCacheManager) FlushTenant(tenant string) int {
+	d.tenant = tenant
+	n := 0
+	for key := range d.entries {
+		if strings.HasPrefix(key, tenant+"/") {
+			delete(d.entries, key)
+			n++
+		}
+	}
+	return n
+}
+
+type memorySink struct {
+	events []audit.Event
+}
+
+func (m *memorySink) Write(e audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *memorySink) Close() error { return nil }
+
+func TestCacheStatsHandler(t *testing.T) {
+	stats := &dummyCacheManager{}
+	h := handler.NewCacheHandler(stats, nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/cache/stats?top=5", nil)
+	w := httptest.NewRecorder()
+
+	h.Stats(w, req)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status OK, got %v", resp.StatusCode)
+	}
+	if stats.topN != 5 {
+		t.Errorf("Expected top 5 keys to be requested, got %d", stats.topN)
+	}
+	var body cache.Stats
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Entries != 2 || body.PriorityEntries != 1 {
+		t.Errorf("Unexpected stats in response: %+v", body)
+	}
+}
+
+func TestCacheStatsHandler_InvalidTop(t *testing.T) {
+	h := handler.NewCacheHandler(&dummyCacheManager{}, nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/cache/stats?top=abc", nil)
+	w := httptest.NewRecorder()
+
+	h.Stats(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status BadRequest, got %v", w.Code)
+	}
+}
+
+func TestCacheDeleteHandler(t *testing.T) {
+	sink := &memorySink{}
+	h := handler.NewCacheHandler(&dummyCacheManager{entries: map[string]bool{"k1": true}}, audit.NewLogger(sink))
+
+	for _, tt := range []struct {
+		key  string
+		want int
+	}{
+		{key: "k1", want: http.StatusNoContent},
+		{key: "k1", want: http.StatusNotFound},
+	} {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/v1/cache/entries/"+tt.key, nil), map[string]string{"key": tt.key})
+		w := httptest.NewRecorder()
+		h.Delete(w, req)
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d deleting %s, got %d", tt.want, tt.key, w.Code)
+		}
+	}
+
+	if len(sink.events) != 1 {
+		t.Fatalf("Expected 1 audit event, got %d", len(sink.events))
+	}
+	if e := sink.events[0]; e.Action != audit.ActionCacheDelete || e.Source != audit.SourceAdmin || e.Key != "k1" {
+		t.Errorf("Unexpected audit event: %+v", e)
+	}
+}
+
+func TestCacheFlushHandler(t *testing.T) {
+	sink := &memorySink{}
+	h := handler.NewCacheHandler(&dummyCacheManager{entries: map[string]bool{"k1": true, "k2": true}}, audit.NewLogger(sink))
+	w := httptest.NewRecorder()
+
+	h.Flush(w, httptest.NewRequest(http.MethodPost, "/v1/cache/flush", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status OK, got %v", w.Code)
+	}
+	if len(sink.events) != 1 || sink.events[0].Action != audit.ActionCacheFlush || sink.events[0].Count != 2 {
+		t.Errorf("Expected a flush audit event removing 2 entries, got %+v", sink.events)
+	}
+}
+
+func TestCacheHandler_TenantScope(t *testing.T) {
+	sink := &memorySink{}
+	manager := &dummyCacheManager{entries: map[string]bool{"acme/k1": true, "acme/k2": true, "globex/k1": true}}
+	h := handler.NewCacheHandler(manager, audit.NewLogger(sink))
+
+	w := httptest.NewRecorder()
+	h.Stats(w, httptest.NewRequest(http.MethodGet, "/v1/cache/stats?tenant=acme", nil))
+	if w.Code != http.StatusOK || manager.tenant != "acme" {
+		t.Errorf("Expected stats for tenant acme, got status %d tenant %q", w.Code, manager.tenant)
+	}
+
+	w = httptest.NewRecorder()
+	h.Flush(w, httptest.NewRequest(http.MethodPost, "/v1/cache/flush?tenant=acme", nil))
+	if len(manager.entries) != 1 || !manager.entries["globex/k1"] {
+		t.Errorf("Expected only acme entries to be flushed, left %v", manager.entries)
+	}
+	if len(sink.events) != 1 || sink.events[0].Tenant != "acme" || sink.events[0].Count != 2 {
+		t.Errorf("Expected a tenant flush audit event removing 2 entries, got %+v", sink.events)
+	}
+}
+
+func TestCacheHandler_TenantBoundCaller(t *testing.T) {
+	manager := &dummyCacheManager{entries: map[string]bool{"acme/k1": true, "globex/k1": true}}
+	h := handler.NewCacheHandler(manager, nil)
+	asAcme := func(r *http.Request) *http.Request {
+		id := auth.Identity{ClientID: "acme-ops", Scopes: []string{auth.ScopeCacheAdmin}, Tenant: "acme"}
+		return r.WithContext(auth.WithIdentity(r.Context(), id))
+	}
+
+	w := httptest.NewRecorder()
+	h.Stats(w, asAcme(httptest.NewRequest(http.MethodGet, "/v1/cache/stats", nil)))
+	if w.Code != http.StatusOK || manager.tenant != "acme" {
+		t.Errorf("Expected untenanted stats to be scoped to acme, got status %d tenant %q", w.Code, manager.tenant)
+	}
+
+	w = httptest.NewRecorder()
+	h.Stats(w, asAcme(httptest.NewRequest(http.MethodGet, "/v1/cache/stats?tenant=globex", nil)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status Forbidden for another tenant's stats, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Flush(w, asAcme(httptest.NewRequest(http.MethodPost, "/v1/cache/flush?tenant=globex", nil)))
+	if w.Code != http.StatusForbidden || !manager.entries["globex/k1"] {
+		t.Errorf("Expected status Forbidden flushing another tenant, got %d", w.Code)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/v1/cache/entries/globex%2Fk1", nil), map[string]string{"key": "globex/k1"})
+	w = httptest.NewRecorder()
+	h.Delete(w, asAcme(req))
+	if w.Code != http.StatusNotFound || !manager.entries["globex/k1"] {
+		t.Errorf("Expected status NotFound deleting another tenant's entry, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Flush(w, asAcme(httptest.NewRequest(http.MethodPost, "/v1/cache/flush", nil)))
+	if w.Code != http.StatusOK || manager.entries["acme/k1"] || !manager.entries["globex/k1"] {
+		t.Errorf("Expected untenanted flush to remove only acme entries, left %v", manager.entries)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+)
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	GoVersion string            `json:"goVersion"`
+	Path      string            `json:"path,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"`
+	StartedAt time.Time         `json:"startedAt"`
+	Uptime    string            `json:"uptime"`
+}
+
+// RuntimeStats reports scheduler, memory and garbage collector state.
+type RuntimeStats struct {
+	Goroutines     int       `json:"goroutines"`
+	NumCPU         int       `json:"numCpu"`
+	GOMAXPROCS     int       `json:"gomaxprocs"`
+	HeapAllocBytes uint64    `json:"heapAllocBytes"`
+	HeapInuseBytes uint64    `json:"heapInuseBytes"`
+	HeapObjects    uint64    `json:"heapObjects"`
+	SysBytes       uint64    `json:"sysBytes"`
+	NumGC          uint32    `json:"numGc"`
+	LastGC         time.Time `json:"lastGc"`
+	PauseTotalMs   float64   `json:"pauseTotalMs"`
+	NextGCBytes    uint64    `json:"nextGcBytes"`
+}
+
+// DiagnosticsHandler serves build, runtime and configuration diagnostics.
+type DiagnosticsHandler struct {
+	startedAt time.Time
+	config    interface{}
+}
+
+// NewDiagnosticsHandler creates a new DiagnosticsHandler. config is served as
+// is, so callers must redact secrets before passing it in.
+func NewDiagnosticsHandler(config interface{}) *DiagnosticsHandler {
+	return &DiagnosticsHandler{startedAt: time.Now(), config: config}
+}
+
+// BuildInfo handles the build information endpoint.
+func (h *DiagnosticsHandler) BuildInfo(w http.ResponseWriter, r *http.Request) {
+	info := BuildInfo{
+		GoVersion: runtime.Version(),
+		StartedAt: h.startedAt,
+		Uptime:    time.Since(h.startedAt).Round(time.Second).String(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Path = bi.Main.Path
+		info.Version = bi.Main.Version
+		info.Settings = make(map[string]string, len(bi.Settings))
+		for _, s := range bi.Settings {
+			info.Settings[s.Key] = s.Value
+		}
+	}
+	respondWithJSON(w, info)
+}
+
+// RuntimeStats handles the runtime statistics endpoint.
+func (h *DiagnosticsHandler) RuntimeStats(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := RuntimeStats{
+		Goroutines:     runtime.NumGoroutine(),
+		NumCPU:         runtime.NumCPU(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		PauseTotalMs:   float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		NextGCBytes:    mem.NextGC,
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC)).UTC()
+	}
+	respondWithJSON(w, stats)
+}
+
+// Config handles the configuration dump endpoint.
+func (h *DiagnosticsHandler) Config(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, h.config)
+}
+
+// respondWithJSON sends a successful JSON response.
+func respondWithJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		respondWithError(w, errors.NewInternalError("failed to encode response", err))
+		return
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/jobs"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+// Default limits applied to batch job submissions and result pages.
+const (
+	DefaultJobMaxBodyBytes int64 = 64 << 20
+	DefaultJobMaxRows            = 100000
+	defaultJobPageSize           = 1000
+	maxJobPageSize               = 10000
+)
+
+// JobManager queues batch measurement jobs and reports on their progress.
+type JobManager interface {
+	Submit(ctx context.Context, rows []models.MeasureRow, opts ...jobs.SubmitOption) (jobs.Job, error)
+	Get(id string) (jobs.Job, bool)
+	Results(id string, offset, limit int) ([]models.MeasureRowResponse, int, bool)
+}
+
+// JobWebhooks validates job callback URLs and reports their deliveries.
+type JobWebhooks interface {
+	ValidateURL(rawURL string) error
+	Deliveries(jobID string) []webhook.Delivery
+}
+
+// JobResponse is a job's status together with a page of its results.
+type JobResponse struct {
+	jobs.Job
+	Offset     int                         `json:"offset"`
+	Limit      int                         `json:"limit"`
+	NextOffset *int                        `json:"nextOffset,omitempty"`
+	Results    []models.MeasureRowResponse `json:"results"`
+}
+
+// JobsHandlerOption defines a functional option for configuring the JobsHandler.
+type JobsHandlerOption func(*JobsHandler)
+
+// WithJobWebhooks accepts callback URLs on job submissions and reports their
+// delivery log.
+func WithJobWebhooks(webhooks JobWebhooks) JobsHandlerOption {
+	return func(h *JobsHandler) {
+		h.webhooks = webhooks
+	}
+}
+
+// JobsHandler handles HTTP requests for asynchronous batch measurement.
+type JobsHandler struct {
+	manager      JobManager
+	webhooks     JobWebhooks
+	maxBodyBytes int64
+	maxRows      int
+}
+
+// NewJobsHandler creates a new JobsHandler. Non-positive limits keep the defaults.
+func NewJobsHandler(manager JobManager, maxBodyBytes int64, maxRows int, opts ...JobsHandlerOption) *JobsHandler {
+	h := &JobsHandler{
+		manager:      manager,
+		maxBodyBytes: DefaultJobMaxBodyBytes,
+		maxRows:      DefaultJobMaxRows,
+	}
+	if maxBodyBytes > 0 {
+		h.maxBodyBytes = maxBodyBytes
+	}
+	if maxRows > 0 {
+		h.maxRows = maxRows
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Create handles submission of a batch job. It responds 202 Accepted with the
+// queued job and its URL in the Location header. Without a callbackUrl in the
+// request, the caller's default callback URL, if any, is notified. Anonymous
+// callers are refused, as their jobs would have no owner.
+func (h *JobsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if requestctx.FromContext(r.Context()).ClientID() == "" {
+		respondWithError(w, errors.NewUnauthorizedError("batch jobs require an authenticated client"))
+		return
+	}
+
+	var req models.JobRequest
+	err := decodeStrict(w, r, h.maxBodyBytes, &req)
+	if err == nil {
+		err = checkRowCount(len(req.Rows), h.maxRows)
+	}
+	if err == nil {
+		err = authorizePriority(r, req.Rows, 0)
+	}
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	callbackURL := req.CallbackURL
+	if id, ok := auth.FromContext(r.Context()); ok && callbackURL == "" {
+		callbackURL = id.CallbackURL
+	}
+	var opts []jobs.SubmitOption
+	if callbackURL != "" {
+		if h.webhooks == nil {
+			respondWithError(w, errors.NewValidationError("webhooks are not enabled"))
+			return
+		}
+		if err := h.webhooks.ValidateURL(callbackURL); err != nil {
+			respondWithError(w, err)
+			return
+		}
+		opts = append(opts, jobs.WithCallbackURL(callbackURL))
+	}
+
+	job, err := h.manager.Submit(r.Context(), req.Rows, opts...)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	requestctx.FromContext(r.Context()).SetRows(len(req.Rows), 0)
+
+	w.Header().Set("Location", "/v1/emissions/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	respondWithJSON(w, job)
+}
+
+// Get handles retrieval of a job's status and results. The optional "offset"
+// and "limit" query parameters page through the results. Jobs submitted by
+// other clients are reported as not found.
+func (h *JobsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0, 0, -1)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultJobPageSize, 1, maxJobPageSize)
+	if err != nil {
+		respondWithError(w, err)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	job, ok := h.ownJob(r, id)
+	if !ok {
+		respondWithError(w, errors.NewNotFoundError("job not found"))
+		return
+	}
+	results, total, _ := h.manager.Results(id, offset, limit)
+
+	resp := JobResponse{Job: job, Offset: offset, Limit: limit, Results: results}
+	// Unfinished jobs may produce more results past the current end.
+	if next := offset + len(results); next < total || !job.Done() {
+		resp.NextOffset = &next
+	}
+	respondWithJSON(w, resp)
+}
+
+// Deliveries handles retrieval of the callback deliveries made for a job.
+func (h *JobsHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.ownJob(r, id); !ok {
+		respondWithError(w, errors.NewNotFoundError("job not found"))
+		return
+	}
+	deliveries := []webhook.Delivery{}
+	if h.webhooks != nil {
+		deliveries = h.webhooks.Deliveries(id)
+	}
+	respondWithJSON(w, map[string][]webhook.Delivery{"deliveries": deliveries})
+}
+
+// ownJob returns the job with the given ID if it was submitted by the caller,
+// matching both client and tenant. Anonymous callers own no jobs.
+func (h *JobsHandler) ownJob(r *http.Request, id string) (jobs.Job, bool) {
+	info := requestctx.FromContext(r.Context())
+	if info.ClientID() == "" {
+		return jobs.Job{}, false
+	}
+	job, ok := h.manager.Get(id)
+	if !ok || job.ClientID != info.ClientID() || job.Tenant != info.Tenant() {
+		return jobs.Job{}, false
+	}
+	return job, true
+}
+
+// queryInt parses an integer query parameter of at least min, returning def
+// when it is absent. A positive max caps the accepted value.
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || (max > 0 && n > max) {
+		if max > 0 {
+			return 0, errors.NewValidationError(name + " must be an integer between " + strconv.Itoa(min) + " and " + strconv.Itoa(max))
+		}
+		return 0, errors.NewValidationError(name + " must be an integer of at least " + strconv.Itoa(min))
+	}
+	return n, nil
+}
@@ -0,0 +1,184 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/jobs"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+// jobsRouter serves the jobs handler as the given client.
+func jobsRouter(h *handler.JobsHandler, clientID string) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/emissions/jobs", h.Create).Methods("POST")
+	r.HandleFunc("/v1/emissions/jobs/{id}", h.Get).Methods("GET")
+	r.HandleFunc("/v1/emissions/jobs/{id}/deliveries", h.Deliveries).Methods("GET")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, info := requestctx.New(req.Context(), "req")
+		info.SetClientID(clientID)
+		r.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+func TestJobsHandler_CreateAndGet(t *testing.T) {
+	manager := jobs.NewManager(&dummyMeasureService{})
+	h := handler.NewJobsHandler(manager, 0, 2)
+	alice := jobsRouter(h, "alice")
+
+	row := `{"country":"US","channel":"web","impressions":1000,"inventoryId":"inv-001"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/jobs", strings.NewReader(`{"rows":[`+row+`]}`))
+	w := httptest.NewRecorder()
+	alice.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if loc := w.Header().Get("Location"); loc != "/v1/emissions/jobs/"+job.ID {
+		t.Errorf("Expected Location of the new job, got %q", loc)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/emissions/jobs/"+job.ID+"?limit=10", nil)
+	w = httptest.NewRecorder()
+	alice.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body handler.JobResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Status != jobs.StatusQueued || body.Limit != 10 || body.NextOffset == nil || *body.NextOffset != 0 {
+		t.Errorf("Unexpected job response: %+v", body)
+	}
+
+	// Other clients cannot see the job.
+	w = httptest.NewRecorder()
+	jobsRouter(h, "mallory").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/emissions/jobs/"+job.ID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another client's job, got %d", w.Code)
+	}
+}
+
+func TestJobsHandler_Limits(t *testing.T) {
+	h := handler.NewJobsHandler(jobs.NewManager(&dummyMeasureService{}), 0, 1)
+	router := jobsRouter(h, "alice")
+
+	// Callback URLs need webhooks to be enabled.
+	row := `{"country":"US","channel":"web","impressions":1000,"inventoryId":"inv-001"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/jobs", strings.NewReader(`{"rows":[`+row+`],"callbackUrl":"https://hooks.example.com"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a callback URL without webhooks, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/emissions/jobs", strings.NewReader(`{"rows":[`+row+`,`+row+`]}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for too many rows, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/emissions/jobs/missing?limit=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a negative limit, got %d", w.Code)
+	}
+
+	// An empty page would never advance nextOffset.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/emissions/jobs/missing?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a zero limit, got %d", w.Code)
+	}
+}
+
+func TestJobsHandler_Ownership(t *testing.T) {
+	manager := jobs.NewManager(&dummyMeasureService{})
+	h := handler.NewJobsHandler(manager, 0, 0)
+	row := `{"country":"US","channel":"web","impressions":1000,"inventoryId":"inv-001"}`
+
+	w := httptest.NewRecorder()
+	jobsRouter(h, "").ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/emissions/jobs", strings.NewReader(`{"rows":[`+row+`]}`)))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an anonymous submission, got %d", w.Code)
+	}
+
+	ctx, info := requestctx.New(context.Background(), "req")
+	info.SetClientID("ops")
+	info.SetTenant("acme")
+	job, err := manager.Submit(ctx, []models.MeasureRow{{Country: "US", Channel: "web", Impressions: 1000, InventoryID: "inv-001"}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		clientID string
+		tenant   string
+		want     int
+	}{
+		{name: "owner", clientID: "ops", tenant: "acme", want: http.StatusOK},
+		{name: "same client in another tenant", clientID: "ops", tenant: "globex", want: http.StatusNotFound},
+		{name: "anonymous", want: http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.HandleFunc("/v1/emissions/jobs/{id}", h.Get).Methods("GET")
+			req := httptest.NewRequest(http.MethodGet, "/v1/emissions/jobs/"+job.ID, nil)
+			ctx, info := requestctx.New(req.Context(), "req")
+			info.SetClientID(tt.clientID)
+			info.SetTenant(tt.tenant)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req.WithContext(ctx))
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestJobsHandler_CallbackAndDeliveries(t *testing.T) {
+	notifier := webhook.NewNotifier("handler-secret", webhook.WithAllowedHosts([]string{"hooks.example.com"}))
+	h := handler.NewJobsHandler(jobs.NewManager(&dummyMeasureService{}), 0, 0, handler.WithJobWebhooks(notifier))
+	alice := jobsRouter(h, "alice")
+
+	row := `{"country":"US","channel":"web","impressions":1000,"inventoryId":"inv-001"}`
+	w := httptest.NewRecorder()
+	alice.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/emissions/jobs", strings.NewReader(`{"rows":[`+row+`],"callbackUrl":"https://evil.example.com"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a disallowed callback host, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	alice.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/emissions/jobs", strings.NewReader(`{"rows":[`+row+`],"callbackUrl":"https://hooks.example.com/jobs"}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var job jobs.Job
+	json.NewDecoder(w.Body).Decode(&job)
+	if job.CallbackURL != "https://hooks.example.com/jobs" {
+		t.Errorf("Expected the callback URL on the job, got %q", job.CallbackURL)
+	}
+
+	w = httptest.NewRecorder()
+	alice.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/emissions/jobs/"+job.ID+"/deliveries", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"deliveries":[]`) {
+		t.Errorf("Expected an empty delivery log, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+var _ handler.JobManager = (*jobs.Manager)(nil)
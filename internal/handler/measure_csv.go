@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"encoding/csv"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/service"
+)
+
+// ContentTypeCSV is accepted for measure requests and, when preferred by the
+// Accept header, used for measure responses.
+const ContentTypeCSV = "text/csv"
+
+// maxCSVIssues bounds how many invalid lines are listed in one error.
+const maxCSVIssues = 20
+
+// csvColumns maps the lower-cased CSV header names, which follow the JSON
+// field names, to the MeasureRow field they set.
+var csvColumns = map[string]func(row *models.MeasureRow, value string) string{
+	"country": func(row *models.MeasureRow, value string) string {
+		row.Country = value
+		return ""
+	},
+	"channel": func(row *models.MeasureRow, value string) string {
+		row.Channel = value
+		return ""
+	},
+	"impressions": func(row *models.MeasureRow, value string) string {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Sprintf("impressions %q is not an integer", value)
+		}
+		row.Impressions = n
+		return ""
+	},
+	"inventoryid": func(row *models.MeasureRow, value string) string {
+		row.InventoryID = value
+		return ""
+	},
+	"utcdatetime": func(row *models.MeasureRow, value string) string {
+		row.UTCDatetime = value
+		return ""
+	},
+	"ispriority": func(row *models.MeasureRow, value string) string {
+		if value == "" {
+			return ""
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Sprintf("isPriority %q is not a boolean", value)
+		}
+		row.IsPriority = b
+		return ""
+	},
+}
+
+// isCSV reports whether the request body is CSV.
+func isCSV(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == ContentTypeCSV
+}
+
+// acceptsCSV reports whether the Accept header prefers CSV over JSON. Ties go
+// to the type listed first.
+func acceptsCSV(r *http.Request) bool {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || (mediaType != ContentTypeCSV && mediaType != "application/json") {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best == ContentTypeCSV
+}
+
+// decodeCSVRequest reads a CSV body whose header row names MeasureRow fields.
+// Every invalid line is reported, by line number, in a single error.
+func decodeCSVRequest(w http.ResponseWriter, r *http.Request, maxBodyBytes int64, maxRows int) (models.MeasureRequest, error) {
+	var req models.MeasureRequest
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return req, errors.NewValidationError("no rows provided in request")
+	}
+	if err != nil {
+		return req, csvReadError(err, maxBodyBytes)
+	}
+	setters, err := csvSetters(header)
+	if err != nil {
+		return req, err
+	}
+
+	var issues []errors.FieldError
+	for records := 1; ; records++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if records > maxRows {
+			return req, errors.NewPayloadTooLargeError(fmt.Sprintf("request contains more than %d rows", maxRows))
+		}
+		var parseErr *csv.ParseError
+		if stderrors.As(err, &parseErr) && stderrors.Is(err, csv.ErrFieldCount) {
+			issues = append(issues, csvIssue(parseErr.StartLine, fmt.Sprintf("expected %d fields, got %d", len(header), len(record))))
+			continue
+		}
+		if err != nil {
+			return req, csvReadError(err, maxBodyBytes)
+		}
+		line, _ := reader.FieldPos(0)
+
+		var row models.MeasureRow
+		problem := ""
+		for i, set := range setters {
+			if problem = set(&row, strings.TrimSpace(record[i])); problem != "" {
+				break
+			}
+		}
+		if problem == "" {
+			_, problem = service.RowProblem(row)
+		}
+		if problem != "" {
+			issues = append(issues, csvIssue(line, problem))
+			continue
+		}
+		req.Rows = append(req.Rows, row)
+	}
+
+	if len(issues) > 0 {
+		reported := issues[:min(len(issues), maxCSVIssues)]
+		descriptions := make([]string, len(reported))
+		for i, issue := range reported {
+			descriptions[i] = issue.Field + ": " + issue.Message
+		}
+		msg := "invalid CSV: " + strings.Join(descriptions, "; ")
+		if len(issues) > maxCSVIssues {
+			msg += fmt.Sprintf(" (and %d more)", len(issues)-maxCSVIssues)
+		}
+		return req, errors.NewFieldValidationError(msg, reported...)
+	}
+	if len(req.Rows) == 0 {
+		return req, errors.NewValidationError("no rows provided in request")
+	}
+	return req, nil
+}
+
+// csvSetters resolves the header row to one field setter per column,
+// rejecting unknown, duplicate and missing required columns. A leading byte
+// order mark, common in spreadsheet exports, is ignored.
+func csvSetters(header []string) ([]func(*models.MeasureRow, string) string, error) {
+	setters := make([]func(*models.MeasureRow, string) string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		set, ok := csvColumns[key]
+		if !ok {
+			return nil, csvHeaderError(fmt.Sprintf("unknown column %q", name))
+		}
+		if seen[key] {
+			return nil, csvHeaderError(fmt.Sprintf("duplicate column %q", name))
+		}
+		seen[key] = true
+		setters[i] = set
+	}
+	for _, required := range []string{"country", "channel", "impressions", "inventoryId"} {
+		if !seen[strings.ToLower(required)] {
+			return nil, csvHeaderError(fmt.Sprintf("missing column %q", required))
+		}
+	}
+	return setters, nil
+}
+
+// csvIssue describes a problem with a CSV line, counting the header as line 1.
+func csvIssue(line int, problem string) errors.FieldError {
+	return errors.FieldError{Field: fmt.Sprintf("line %d", line), Message: problem}
+}
+
+// csvHeaderError reports a problem with the header line.
+func csvHeaderError(problem string) error {
+	issue := csvIssue(1, problem)
+	return errors.NewFieldValidationError(issue.Field+": "+issue.Message, issue)
+}
+
+// csvReadError converts a CSV reader error to a service error.
+func csvReadError(err error, maxBodyBytes int64) error {
+	if isTooLarge(err) {
+		return errors.NewPayloadTooLargeError(fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes))
+	}
+	return errors.NewValidationError(fmt.Sprintf("invalid CSV: %v", err))
+}
+
+// respondWithCSV writes the result rows as CSV. The total is sent in the
+// X-Total-Emissions header and each warning in an X-Warning header.
+func respondWithCSV(w http.ResponseWriter, response *models.MeasureResponse) {
+	w.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	w.Header().Set("X-Total-Emissions", formatFloat(response.TotalEmissions))
+	for _, warning := range response.Warnings {
+		w.Header().Add("X-Warning", warning)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"propertyId", "propertyName", "totalEmissions", "cached", "inventoryCoverage"})
+	for _, row := range response.Rows {
+		cw.Write([]string{
+			strconv.Itoa(row.PropertyID),
+			row.PropertyName,
+			formatFloat(row.TotalEmissions),
+			strconv.FormatBool(row.Cached),
+			row.InventoryCoverage,
+		})
+	}
+	cw.Flush()
+}
+
+// formatFloat formats f in the shortest form that round-trips.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
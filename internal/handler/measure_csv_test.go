@@ -0,0 +1,128 @@
+package handler_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/handler"
+)
+
+func csvRequest(body, accept string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return req
+}
+
+func TestMeasureCSV_RoundTrip(t *testing.T) {
+	h := handler.NewMeasureHandler(&chunkEchoService{})
+	body := "\ufeffCountry,channel,impressions,inventoryId,isPriority\n" +
+		"US,web,1000,cached-a,false\n" +
+		"\"GB\", app ,2000,b,\n"
+	w := httptest.NewRecorder()
+	h.Measure(w, csvRequest(body, "application/json;q=0.5, text/csv"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status OK, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected a CSV response, got %q", ct)
+	}
+	if total := w.Header().Get("X-Total-Emissions"); total != "2" {
+		t.Errorf("Expected X-Total-Emissions 2, got %q", total)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV response: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "propertyId" || records[1][1] != "cached-a" || records[1][3] != "true" || records[2][1] != "b" {
+		t.Errorf("Unexpected CSV response: %v", records)
+	}
+}
+
+func TestMeasureCSV_JSONResponseByDefault(t *testing.T) {
+	h := handler.NewMeasureHandler(&chunkEchoService{})
+	w := httptest.NewRecorder()
+	h.Measure(w, csvRequest("country,channel,impressions,inventoryId\nUS,web,1000,a\n", "*/*"))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a JSON response, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+}
+
+func TestMeasureCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		code    int
+		message []string
+	}{
+		{
+			name:    "unknown column",
+			body:    "country,channel,impressions,inventoryId,colour\n",
+			code:    http.StatusBadRequest,
+			message: []string{`line 1: unknown column "colour"`},
+		},
+		{
+			name:    "missing column",
+			body:    "country,channel,impressions\n",
+			code:    http.StatusBadRequest,
+			message: []string{`line 1: missing column "inventoryId"`},
+		},
+		{
+			name: "invalid lines",
+			body: "country,channel,impressions,inventoryId\n" +
+				"US,web,1000,a\n" +
+				"US,web,many,b\n" +
+				",web,1000,c\n" +
+				"US,web,1000\n",
+			code: http.StatusBadRequest,
+			message: []string{
+				`line 3: impressions "many" is not an integer`,
+				"line 4: country is required",
+				"line 5: expected 4 fields, got 3",
+			},
+		},
+		{
+			name:    "no rows",
+			body:    "country,channel,impressions,inventoryId\n",
+			code:    http.StatusBadRequest,
+			message: []string{"no rows provided"},
+		},
+		{
+			name:    "too many rows",
+			body:    "country,channel,impressions,inventoryId\n" + strings.Repeat("US,web,1,a\n", 6),
+			code:    http.StatusRequestEntityTooLarge,
+			message: []string{"more than 5 rows"},
+		},
+	}
+
+	h := handler.NewMeasureHandler(&chunkEchoService{}, handler.WithRequestLimits(0, 5))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Measure(w, csvRequest(tt.body, "text/csv"))
+			if w.Code != tt.code {
+				t.Fatalf("Expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			var problem errors.Problem
+			json.NewDecoder(w.Body).Decode(&problem)
+			for _, msg := range tt.message {
+				if !strings.Contains(problem.Detail, msg) {
+					t.Errorf("Expected error to mention %q, got %q", msg, problem.Detail)
+				}
+			}
+		})
+	}
+}
@@ -2,21 +2,94 @@ package handler
 
 import (
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"emissions-cache-service/internal/auth"
 	"emissions-cache-service/internal/errors"
 	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
 	"emissions-cache-service/internal/service"
+	"emissions-cache-service/internal/slo"
+	"emissions-cache-service/pkg/redact"
 )
 
+// Default limits applied to measure requests.
+const (
+	DefaultMaxBodyBytes int64 = 1 << 20
+	DefaultMaxRows            = 1000
+)
+
+// LatencyRecorder receives the latency of each measure request.
+type LatencyRecorder interface {
+	Observe(path slo.Path, latency time.Duration, failed bool)
+}
+
+// MeasureHandlerOption defines a functional option for configuring the MeasureHandler.
+type MeasureHandlerOption func(*MeasureHandler)
+
+// WithLatencyRecorder records the latency of every measure request.
+func WithLatencyRecorder(recorder LatencyRecorder) MeasureHandlerOption {
+	return func(h *MeasureHandler) {
+		h.latency = recorder
+	}
+}
+
+// WithRequestLimits caps the size of a measure request body and the number of
+// rows it may contain. Non-positive values keep the defaults.
+func WithRequestLimits(maxBodyBytes int64, maxRows int) MeasureHandlerOption {
+	return func(h *MeasureHandler) {
+		if maxBodyBytes > 0 {
+			h.maxBodyBytes = maxBodyBytes
+		}
+		if maxRows > 0 {
+			h.maxRows = maxRows
+		}
+	}
+}
+
+// WithErrorDetails includes the redacted underlying error as the "cause"
+// field of error responses. It may reveal upstream responses or internal
+// state, so it should only be enabled in development.
+func WithErrorDetails(enabled bool) MeasureHandlerOption {
+	return func(h *MeasureHandler) {
+		h.errorDetails = enabled
+	}
+}
+
 // MeasureHandler handles HTTP requests for emissions measurement.
 type MeasureHandler struct {
 	measureService service.MeasureService
+	latency        LatencyRecorder
+	maxBodyBytes   int64
+	maxRows        int
+	errorDetails   bool
+
+	streamMaxBodyBytes int64
+	streamMaxRows      int
+	streamChunkSize    int
 }
 
 // NewMeasureHandler creates a new MeasureHandler.
-func NewMeasureHandler(ms service.MeasureService) *MeasureHandler {
-	return &MeasureHandler{measureService: ms}
+func NewMeasureHandler(ms service.MeasureService, opts ...MeasureHandlerOption) *MeasureHandler {
+	h := &MeasureHandler{
+		measureService: ms,
+		maxBodyBytes:   DefaultMaxBodyBytes,
+		maxRows:        DefaultMaxRows,
+
+		streamMaxBodyBytes: DefaultStreamMaxBodyBytes,
+		streamMaxRows:      DefaultStreamMaxRows,
+		streamChunkSize:    DefaultStreamChunkSize,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // HealthCheck handles the health check endpoint.
@@ -25,42 +98,190 @@ func (h *MeasureHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// Measure handles the emissions measurement endpoint.
+// Measure handles the emissions measurement endpoint. Requests sent as
+// application/x-ndjson are streamed one row per line in both directions. CSV
+// bodies are accepted too, and results are written as CSV when the Accept
+// header prefers it.
 func (h *MeasureHandler) Measure(w http.ResponseWriter, r *http.Request) {
+	if isNDJSON(r) {
+		h.measureStream(w, r)
+		return
+	}
+
 	var req models.MeasureRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, errors.NewValidationError("invalid JSON request"))
+	var err error
+	if isCSV(r) {
+		req, err = decodeCSVRequest(w, r, h.maxBodyBytes, h.maxRows)
+	} else {
+		req, err = decodeMeasureRequest(w, r, h.maxBodyBytes, h.maxRows)
+	}
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+	if err := authorizePriority(r, req.Rows, 0); err != nil {
+		h.respondWithError(w, err)
 		return
 	}
 
+	start := time.Now()
 	response, err := h.measureService.GetMeasure(r.Context(), req)
+	h.observeLatency(time.Since(start), response, err)
+	requestctx.FromContext(r.Context()).SetRows(len(req.Rows), countCached(response))
 	if err != nil {
-		respondWithError(w, err)
+		h.respondWithError(w, err)
 		return
 	}
 
+	if acceptsCSV(r) {
+		respondWithCSV(w, response)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		respondWithError(w, errors.NewInternalError("failed to encode response", err))
+		h.respondWithError(w, errors.NewInternalError("failed to encode response", err))
 		return
 	}
 }
 
-// respondWithError sends an error response in JSON format.
-// It includes error details for development purposes.
-func respondWithError(w http.ResponseWriter, err error) {
-	code, message := errors.ToHTTPError(err)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
+// decodeMeasureRequest strictly decodes a measure request within the given
+// limits.
+func decodeMeasureRequest(w http.ResponseWriter, r *http.Request, maxBodyBytes int64, maxRows int) (models.MeasureRequest, error) {
+	var req models.MeasureRequest
+	if err := decodeStrict(w, r, maxBodyBytes, &req); err != nil {
+		return req, err
+	}
+	return req, checkRowCount(len(req.Rows), maxRows)
+}
 
-	response := map[string]string{
-		"error": message,
+// decodeStrict decodes a JSON body of at most maxBodyBytes into v, rejecting
+// unknown fields and anything after the JSON object.
+func decodeStrict(w http.ResponseWriter, r *http.Request, maxBodyBytes int64, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		if extra := dec.Decode(&struct{}{}); extra != io.EOF {
+			err = fmt.Errorf("unexpected data after JSON object")
+			if isTooLarge(extra) {
+				err = extra
+			}
+		}
+	}
+	if err != nil {
+		if isTooLarge(err) {
+			return errors.NewPayloadTooLargeError(fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes))
+		}
+		return errors.NewValidationError(fmt.Sprintf("invalid JSON request: %v", err))
 	}
+	return nil
+}
 
-	// Include underlying error detail in development mode.
-	if svcErr, ok := err.(*errors.ServiceError); ok && svcErr.Err != nil {
-		response["detail"] = svcErr.Err.Error()
+// checkRowCount rejects requests with more than maxRows rows.
+func checkRowCount(rows, maxRows int) error {
+	if rows > maxRows {
+		return errors.NewPayloadTooLargeError(fmt.Sprintf("request contains %d rows; at most %d are allowed", rows, maxRows))
 	}
+	return nil
+}
 
-	json.NewEncoder(w).Encode(response)
+// isTooLarge reports whether err came from exceeding the body size limit.
+func isTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return stderrors.As(err, &maxErr)
+}
+
+// authorizePriority rejects priority rows from authenticated callers that lack
+// the priority scope, since priority entries are pinned in the cache forever.
+// Rows are numbered from first in the error.
+func authorizePriority(r *http.Request, rows []models.MeasureRow, first int) error {
+	id, ok := auth.FromContext(r.Context())
+	if !ok || id.HasScope(auth.ScopePriority) {
+		return nil
+	}
+	for i, row := range rows {
+		if row.IsPriority {
+			return errors.NewForbiddenError(fmt.Sprintf("isPriority on row %d requires scope %s", first+i, auth.ScopePriority))
+		}
+	}
+	return nil
+}
+
+// observeLatency reports a measure call to the latency recorder, classifying it
+// as cache-only when every returned row was served from the cache. Validation
+// failures, including rows Scope3 rejects, are the caller's fault and are not
+// counted.
+func (h *MeasureHandler) observeLatency(latency time.Duration, response *models.MeasureResponse, err error) {
+	if h.latency == nil {
+		return
+	}
+	if svcErr, ok := errors.As(err); ok && (svcErr.Type == errors.ErrorTypeValidation || svcErr.Type == errors.ErrorTypeUpstreamValidation) {
+		return
+	}
+	path := slo.PathUpstream
+	if response != nil && allCached(response.Rows) {
+		path = slo.PathCached
+	}
+	h.latency.Observe(path, latency, err != nil)
+}
+
+// countCached returns the number of rows served from the cache.
+func countCached(response *models.MeasureResponse) int {
+	if response == nil {
+		return 0
+	}
+	cached := 0
+	for _, row := range response.Rows {
+		if row.Cached {
+			cached++
+		}
+	}
+	return cached
+}
+
+// allCached reports whether every row was served from the cache.
+func allCached(rows []models.MeasureRowResponse) bool {
+	for _, row := range rows {
+		if !row.Cached {
+			return false
+		}
+	}
+	return len(rows) > 0
+}
+
+// WriteError sends an error response in the same format as the handlers, for
+// use by middleware.
+func WriteError(w http.ResponseWriter, err error) {
+	respondWithError(w, err)
+}
+
+// respondWithError sends an error response, including the underlying error
+// when the handler was created WithErrorDetails.
+func (h *MeasureHandler) respondWithError(w http.ResponseWriter, err error) {
+	writeProblem(w, err, h.errorDetails)
+}
+
+// respondWithError sends an error response without the underlying error.
+func respondWithError(w http.ResponseWriter, err error) {
+	writeProblem(w, err, false)
+}
+
+// writeProblem sends an RFC 7807 problem details response carrying the
+// error's stable code and the request ID set by the server middleware.
+// With withCause it includes the redacted underlying error.
+func writeProblem(w http.ResponseWriter, err error, withCause bool) {
+	problem := errors.ToProblem(err)
+	problem.RequestID = w.Header().Get("X-Request-ID")
+
+	svcErr, ok := errors.As(err)
+	if ok && svcErr.Err != nil && withCause {
+		problem.Cause = redact.String(svcErr.Err.Error())
+	}
+
+	w.Header().Set("Content-Type", errors.ProblemContentType)
+	if ok && svcErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(svcErr.RetryAfter.Seconds()))))
+	}
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
 }
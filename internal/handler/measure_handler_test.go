@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"emissions-cache-service/internal/errors"
 	"emissions-cache-service/internal/handler"
 	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/service"
+	"emissions-cache-service/internal/slo"
 )
 
 type dummyMeasureService struct{}
@@ -94,3 +100,185 @@ func TestMeasureHandler_InvalidJSON(t *testing.T) {
 		t.Errorf("Expected status BadRequest for invalid JSON, got %v", resp.StatusCode)
 	}
 }
+
+type recordedLatency struct {
+	path   slo.Path
+	failed bool
+}
+
+type dummyLatencyRecorder struct {
+	observed []recordedLatency
+}
+
+func (d *dummyLatencyRecorder) Observe(path slo.Path, latency time.Duration, failed bool) {
+	d.observed = append(d.observed, recordedLatency{path: path, failed: failed})
+}
+
+type cachedMeasureService struct{}
+
+func (c *cachedMeasureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	return &models.MeasureResponse{
+		RequestID: "cached-id",
+		Rows:      []models.MeasureRowResponse{{PropertyID: 1, Cached: true}},
+	}, nil
+}
+
+func TestMeasureHandler_RecordsLatency(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  service.MeasureService
+		body     string
+		wantPath slo.Path
+		wantObs  int
+	}{
+		{
+			name:     "cache only",
+			service:  &cachedMeasureService{},
+			body:     `{"rows":[]}`,
+			wantPath: slo.PathCached,
+			wantObs:  1,
+		},
+		{
+			name:     "upstream involved",
+			service:  &dummyMeasureService{},
+			body:     `{"rows":[]}`,
+			wantPath: slo.PathUpstream,
+			wantObs:  1,
+		},
+		{
+			name:    "validation failure is not counted",
+			service: service.NewMeasureService(nil, nil),
+			body:    `{"rows":[]}`,
+			wantObs: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &dummyLatencyRecorder{}
+			h := handler.NewMeasureHandler(tt.service, handler.WithLatencyRecorder(recorder))
+			req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.Measure(w, req)
+			if len(recorder.observed) != tt.wantObs {
+				t.Fatalf("Expected %d observations, got %d", tt.wantObs, len(recorder.observed))
+			}
+			if tt.wantObs > 0 && recorder.observed[0].path != tt.wantPath {
+				t.Errorf("Expected path %s, got %s", tt.wantPath, recorder.observed[0].path)
+			}
+		})
+	}
+}
+
+func TestMeasureHandler_StrictDecoding(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown field", `{"rows":[{"country":"US"}],"extra":true}`},
+		{"trailing data", `{"rows":[{"country":"US"}]} {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewMeasureHandler(&dummyMeasureService{})
+			req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.Measure(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status BadRequest, got %v", w.Code)
+			}
+		})
+	}
+}
+
+func TestMeasureHandler_RequestLimits(t *testing.T) {
+	row := `{"country":"US","channel":"online","impressions":1000,"inventoryId":"inv-001"}`
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"within limits", `{"rows":[` + row + `,` + row + `]}`, http.StatusOK},
+		{"too many rows", `{"rows":[` + row + `,` + row + `,` + row + `]}`, http.StatusRequestEntityTooLarge},
+		{"body too large", `{"rows":[` + row + `],"pad":"` + strings.Repeat("x", 512) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler.NewMeasureHandler(&dummyMeasureService{}, handler.WithRequestLimits(512, 2))
+			req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.Measure(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+type failingMeasureService struct {
+	err error
+}
+
+func (f *failingMeasureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	return nil, f.err
+}
+
+func TestMeasureHandler_ErrorDetails(t *testing.T) {
+	svc := &failingMeasureService{err: errors.NewExternalError("upstream failed",
+		fmt.Errorf(`response: {"echo":"Authorization: Bearer abc123"}`))}
+	body := `{"rows":[{"country":"US","channel":"online","impressions":1000,"inventoryId":"inv-001"}]}`
+
+	for _, expose := range []bool{false, true} {
+		h := handler.NewMeasureHandler(svc, handler.WithErrorDetails(expose))
+		w := httptest.NewRecorder()
+		h.Measure(w, httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(body)))
+
+		var resp map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		cause, ok := resp["cause"].(string)
+		if ok != expose {
+			t.Errorf("expose=%v: unexpected cause presence in %v", expose, resp)
+		}
+		if strings.Contains(cause, "abc123") {
+			t.Errorf("expose=%v: expected token to be redacted, got %q", expose, cause)
+		}
+		if _, ok := resp["detail"]; ok {
+			t.Errorf("expose=%v: expected no detail for an upstream error, got %v", expose, resp)
+		}
+	}
+}
+
+func TestMeasureHandler_ProblemDetails(t *testing.T) {
+	svc := &failingMeasureService{err: errors.NewFieldValidationError("impressions must be positive for row 0",
+		errors.FieldError{Field: "/rows/0/impressions", Message: "impressions must be positive"})}
+	body := `{"rows":[{"country":"US","channel":"online","impressions":1000,"inventoryId":"inv-001"}]}`
+	h := handler.NewMeasureHandler(svc)
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "req-123")
+	h.Measure(w, httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(body)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != errors.ProblemContentType {
+		t.Errorf("Expected Content-Type %q, got %q", errors.ProblemContentType, ct)
+	}
+	var problem errors.Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if problem.Code != "VALIDATION_FAILED" || problem.Status != http.StatusBadRequest || problem.Type == "" || problem.Title == "" {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+	if problem.RequestID != "req-123" {
+		t.Errorf("Expected request ID req-123, got %q", problem.RequestID)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "/rows/0/impressions" {
+		t.Errorf("Expected one field error for /rows/0/impressions, got %+v", problem.Errors)
+	}
+}
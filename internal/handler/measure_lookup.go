@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// Lookup resolves a single row described by the path and query string, e.g.
+// GET /v1/emissions/{inventoryId}?country=US&channel=display&impressions=1000.
+// Responses carry a weak ETag over the emissions data and a Cache-Control
+// max-age matching the remaining lifetime of the cache entry, and a matching
+// If-None-Match is answered with 304 Not Modified.
+func (h *MeasureHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	row, err := lookupRow(r)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	start := time.Now()
+	response, err := h.measureService.GetMeasure(r.Context(), models.MeasureRequest{Rows: []models.MeasureRow{row}})
+	h.observeLatency(time.Since(start), response, err)
+	requestctx.FromContext(r.Context()).SetRows(1, countCached(response))
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+	if len(response.Rows) != 1 {
+		h.respondWithError(w, errors.NewInternalError("lookup resolved to an unexpected number of rows", nil))
+		return
+	}
+	result := response.Rows[0]
+
+	etag := rowETag(result)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl(r, result.ExpiresAt, time.Now()))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.respondWithError(w, errors.NewInternalError("failed to encode response", err))
+		return
+	}
+}
+
+// lookupRow builds the measure row for a lookup from its path and query.
+func lookupRow(r *http.Request) (models.MeasureRow, error) {
+	query := r.URL.Query()
+	row := models.MeasureRow{
+		Country:     query.Get("country"),
+		Channel:     query.Get("channel"),
+		InventoryID: mux.Vars(r)["inventoryId"],
+		UTCDatetime: query.Get("date"),
+	}
+	if v := query.Get("impressions"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return row, errors.NewFieldValidationError("impressions must be an integer",
+				errors.FieldError{Field: "impressions", Message: "must be an integer"})
+		}
+		row.Impressions = n
+	}
+	if field, problem := service.RowProblem(row); problem != "" {
+		return row, errors.NewFieldValidationError(problem, errors.FieldError{Field: field, Message: problem})
+	}
+	return row, nil
+}
+
+// rowETag returns a weak entity tag over the emissions data of row. The cached
+// flag and expiry are left out so the tag stays stable while an entry is
+// served from the cache and across refreshes that return the same data.
+func rowETag(row models.MeasureRowResponse) string {
+	data, _ := json.Marshal(models.MeasureRowResponse{
+		PropertyID:        row.PropertyID,
+		PropertyName:      row.PropertyName,
+		TotalEmissions:    row.TotalEmissions,
+		InventoryCoverage: row.InventoryCoverage,
+	})
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// cacheControl lets clients and shared caches keep a lookup for as long as the
+// cache entry behind it lives. Entries without an expiry must be revalidated.
+// Responses to authenticated callers are private, so a shared cache never
+// serves them to a caller without credentials or in another tenant.
+func cacheControl(r *http.Request, expiresAt *time.Time, now time.Time) string {
+	scope := "public"
+	if _, ok := auth.FromContext(r.Context()); ok || requestctx.FromContext(r.Context()).Tenant() != "" {
+		scope = "private"
+	}
+	if expiresAt == nil {
+		return scope + ", no-cache"
+	}
+	maxAge := int(expiresAt.Sub(now) / time.Second)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	return scope + ", max-age=" + strconv.Itoa(maxAge)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the
+// weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
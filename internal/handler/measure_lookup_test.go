@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+
+	"github.com/gorilla/mux"
+)
+
+// lookupService answers with a single row and records the row it was asked for.
+type lookupService struct {
+	expiresAt *time.Time
+	got       models.MeasureRow
+}
+
+func (s *lookupService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	s.got = req.Rows[0]
+	return &models.MeasureResponse{
+		RequestID:      "lookup",
+		TotalEmissions: 12.5,
+		Rows: []models.MeasureRowResponse{{
+			PropertyID:     7,
+			PropertyName:   "Example",
+			TotalEmissions: 12.5,
+			Cached:         true,
+			ExpiresAt:      s.expiresAt,
+		}},
+	}, nil
+}
+
+func lookupRouter(h *handler.MeasureHandler) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/emissions/{inventoryId}", h.Lookup).Methods("GET")
+	return r
+}
+
+func TestMeasureLookup(t *testing.T) {
+	expiresAt := time.Now().Add(90 * time.Second)
+	svc := &lookupService{expiresAt: &expiresAt}
+	router := lookupRouter(handler.NewMeasureHandler(svc))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/emissions/inv-001?country=US&channel=online&impressions=1000&date=2025-01-01T12:00:00Z", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	want := models.MeasureRow{Country: "US", Channel: "online", Impressions: 1000, InventoryID: "inv-001", UTCDatetime: "2025-01-01T12:00:00Z"}
+	if svc.got != want {
+		t.Errorf("Expected row %+v, got %+v", want, svc.got)
+	}
+	var row models.MeasureRowResponse
+	if err := json.NewDecoder(w.Body).Decode(&row); err != nil || row.PropertyID != 7 || row.ExpiresAt == nil {
+		t.Errorf("Expected the row with its expiry, got %+v, %v", row, err)
+	}
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("Expected a weak ETag, got %q", etag)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=89" && cc != "public, max-age=90" {
+		t.Errorf("Expected max-age from the remaining TTL, got %q", cc)
+	}
+
+	// A matching If-None-Match is answered without a body.
+	req = httptest.NewRequest(http.MethodGet, "/v1/emissions/inv-001?country=US&channel=online&impressions=1000", nil)
+	req.Header.Set("If-None-Match", `"other", `+strings.TrimPrefix(etag, "W/"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected 304 with no body, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") == "" {
+		t.Errorf("Expected cache headers on the 304, got %v", w.Header())
+	}
+}
+
+func TestMeasureLookup_CacheControl(t *testing.T) {
+	// Entries that never expire must be revalidated, and tenant responses are private.
+	router := lookupRouter(handler.NewMeasureHandler(&lookupService{}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/emissions/inv-001?country=US&channel=online&impressions=1000", nil)
+	ctx, info := requestctx.New(req.Context(), "req-1")
+	info.SetTenant("acme")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req.WithContext(ctx))
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("Expected private, no-cache, got %q", cc)
+	}
+
+	// So are responses to authenticated callers without a tenant.
+	req = httptest.NewRequest(http.MethodGet, "/v1/emissions/inv-001?country=US&channel=online&impressions=1000", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{ClientID: "dashboard"})))
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("Expected private, no-cache for an authenticated caller, got %q", cc)
+	}
+}
+
+func TestMeasureLookup_Validation(t *testing.T) {
+	router := lookupRouter(handler.NewMeasureHandler(&lookupService{}))
+	tests := map[string]string{
+		"/v1/emissions/inv-001?channel=online&impressions=1000":            "country is required",
+		"/v1/emissions/inv-001?country=US&channel=online&impressions=lots": "impressions must be an integer",
+		"/v1/emissions/inv-001?country=US&channel=online":                  "impressions must be positive",
+		"/v1/emissions/inv-001?country=US&channel=online&impressions=-1":   "impressions must be positive",
+	}
+	for target, message := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), message) {
+			t.Errorf("%s: expected 400 %q, got %d: %s", target, message, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/service"
+)
+
+// ContentTypeNDJSON selects the streaming mode of the measure endpoint.
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// Default limits applied to streamed measure requests.
+const (
+	DefaultStreamMaxBodyBytes int64 = 64 << 20
+	DefaultStreamMaxRows            = 100000
+	DefaultStreamChunkSize          = 500
+)
+
+// WithStreamLimits caps the body size and row count of streamed measure
+// requests and sets how many rows are resolved at a time. Non-positive values
+// keep the defaults.
+func WithStreamLimits(maxBodyBytes int64, maxRows, chunkSize int) MeasureHandlerOption {
+	return func(h *MeasureHandler) {
+		if maxBodyBytes > 0 {
+			h.streamMaxBodyBytes = maxBodyBytes
+		}
+		if maxRows > 0 {
+			h.streamMaxRows = maxRows
+		}
+		if chunkSize > 0 {
+			h.streamChunkSize = chunkSize
+		}
+	}
+}
+
+// StreamSummary is the last line of a streamed measure response.
+type StreamSummary struct {
+	RequestID      string   `json:"requestId"`
+	TotalRows      int      `json:"totalRows"`
+	CachedRows     int      `json:"cachedRows"`
+	TotalEmissions float64  `json:"totalEmissions"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// StreamError reports a failure after a streamed response has started.
+type StreamError struct {
+	Status int `json:"status"`
+	// Code is the stable error code also used in problem details responses.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// streamTrailer is a summary or error line; result lines are plain
+// models.MeasureRowResponse objects.
+type streamTrailer struct {
+	Summary *StreamSummary `json:"summary,omitempty"`
+	Error   *StreamError   `json:"error,omitempty"`
+}
+
+// isNDJSON reports whether the request body is newline-delimited JSON.
+func isNDJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == ContentTypeNDJSON
+}
+
+// measureStream reads one row per line and resolves them in chunks, writing
+// each chunk's results as soon as they are available and a summary line last.
+// Failures before the first result get a regular error response; later ones
+// end the stream with an error line. Streamed requests are not timed against
+// the latency SLO since their duration depends on the client's upload.
+func (h *MeasureHandler) measureStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	info := requestctx.FromContext(ctx)
+	rc := http.NewResponseController(w)
+	// Keep reading rows after results have been written. HTTP/2 always
+	// allows this; the error only means HTTP/1 support is unavailable.
+	_ = rc.EnableFullDuplex()
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.streamMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	enc := json.NewEncoder(w)
+	summary := StreamSummary{RequestID: info.RequestID()}
+	started := false
+	fail := func(err error) {
+		info.SetRows(summary.TotalRows, summary.CachedRows)
+		if !started {
+			h.respondWithError(w, err)
+			return
+		}
+		status, message := errors.ToHTTPError(err)
+		enc.Encode(streamTrailer{Error: &StreamError{Status: status, Code: errors.ToProblem(err).Code, Message: message}})
+	}
+
+	read := 0
+	chunk := make([]models.MeasureRow, 0, h.streamChunkSize)
+	for eof := false; !eof; {
+		chunk = chunk[:0]
+		for len(chunk) < h.streamChunkSize {
+			row, err := h.decodeStreamRow(dec, read)
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err == nil {
+				err = authorizePriority(r, []models.MeasureRow{row}, read)
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+			chunk = append(chunk, row)
+			read++
+		}
+		if len(chunk) == 0 {
+			break
+		}
+
+		resp, err := h.measureService.GetMeasure(ctx, models.MeasureRequest{Rows: chunk})
+		if err != nil {
+			fail(err)
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", ContentTypeNDJSON)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		for _, row := range resp.Rows {
+			enc.Encode(row)
+		}
+		summary.TotalRows += len(chunk)
+		summary.CachedRows += countCached(resp)
+		summary.TotalEmissions += resp.TotalEmissions
+		summary.Warnings = append(summary.Warnings, resp.Warnings...)
+		rc.Flush()
+	}
+
+	if read == 0 {
+		fail(errors.NewValidationError("no rows provided in request"))
+		return
+	}
+	info.SetRows(summary.TotalRows, summary.CachedRows)
+	enc.Encode(streamTrailer{Summary: &summary})
+}
+
+// decodeStreamRow decodes and validates the next row of a streamed request,
+// returning io.EOF once the body is exhausted.
+func (h *MeasureHandler) decodeStreamRow(dec *json.Decoder, index int) (models.MeasureRow, error) {
+	var row models.MeasureRow
+	err := dec.Decode(&row)
+	switch {
+	case err == io.EOF:
+		return row, err
+	case isTooLarge(err):
+		return row, errors.NewPayloadTooLargeError(fmt.Sprintf("request body exceeds %d bytes", h.streamMaxBodyBytes))
+	case err != nil:
+		return row, errors.NewValidationError(fmt.Sprintf("invalid JSON in row %d: %v", index, err))
+	case index >= h.streamMaxRows:
+		return row, errors.NewPayloadTooLargeError(fmt.Sprintf("request contains more than %d rows", h.streamMaxRows))
+	}
+	return row, service.ValidateRow(index, row)
+}
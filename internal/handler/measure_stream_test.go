@@ -0,0 +1,178 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/models"
+)
+
+// chunkEchoService answers each row with one emission, marking rows from
+// "cached-" inventory as cached, and fails chunks containing "fail".
+type chunkEchoService struct {
+	mu     sync.Mutex
+	chunks []int
+}
+
+func (s *chunkEchoService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	s.mu.Lock()
+	s.chunks = append(s.chunks, len(req.Rows))
+	s.mu.Unlock()
+	resp := &models.MeasureResponse{}
+	for _, row := range req.Rows {
+		if row.InventoryID == "fail" {
+			return nil, errors.NewExternalError("upstream unavailable", nil)
+		}
+		resp.Rows = append(resp.Rows, models.MeasureRowResponse{
+			PropertyName:   row.InventoryID,
+			TotalEmissions: 1,
+			Cached:         strings.HasPrefix(row.InventoryID, "cached-"),
+		})
+		resp.TotalEmissions++
+	}
+	return resp, nil
+}
+
+func ndjsonRows(inventoryIDs ...string) string {
+	var b strings.Builder
+	for _, id := range inventoryIDs {
+		fmt.Fprintf(&b, `{"country":"US","channel":"web","impressions":1000,"inventoryId":%q}`+"\n", id)
+	}
+	return b.String()
+}
+
+func streamRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	return req
+}
+
+// streamLine holds any line of a streamed response.
+type streamLine struct {
+	models.MeasureRowResponse
+	Summary *handler.StreamSummary `json:"summary"`
+	Error   *handler.StreamError   `json:"error"`
+}
+
+func readStream(t *testing.T, body io.Reader) []streamLine {
+	t.Helper()
+	var lines []streamLine
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		var line streamLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid response line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestMeasureStream_Success(t *testing.T) {
+	svc := &chunkEchoService{}
+	h := handler.NewMeasureHandler(svc, handler.WithStreamLimits(0, 0, 2))
+	w := httptest.NewRecorder()
+	h.Measure(w, streamRequest(ndjsonRows("cached-a", "b", "c", "cached-d", "e")))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != handler.ContentTypeNDJSON {
+		t.Fatalf("Expected 200 NDJSON response, got %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	lines := readStream(t, w.Body)
+	if len(lines) != 6 {
+		t.Fatalf("Expected 5 result lines and a summary, got %d lines", len(lines))
+	}
+	if lines[0].PropertyName != "cached-a" || lines[4].PropertyName != "e" {
+		t.Errorf("Expected results in row order, got %+v", lines)
+	}
+	summary := lines[5].Summary
+	if summary == nil || summary.TotalRows != 5 || summary.CachedRows != 2 || summary.TotalEmissions != 5 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if len(svc.chunks) != 3 {
+		t.Errorf("Expected rows to be resolved in 3 chunks, got %v", svc.chunks)
+	}
+}
+
+func TestMeasureStream_Errors(t *testing.T) {
+	h := handler.NewMeasureHandler(&chunkEchoService{}, handler.WithStreamLimits(0, 3, 2))
+
+	// Invalid rows before any output get a regular error response.
+	w := httptest.NewRecorder()
+	h.Measure(w, streamRequest(ndjsonRows("a")+`{"country":"US"}`+"\n"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "row 1") {
+		t.Errorf("Expected 400 for row 1, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Measure(w, streamRequest(""))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty stream, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Measure(w, streamRequest(ndjsonRows("a", "b", "c", "d")))
+	lines := readStream(t, w.Body)
+	if last := lines[len(lines)-1]; last.Error == nil || last.Error.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected the stream to end with a 413 error line, got %+v", last)
+	}
+
+	// Failures after output has started end the stream with an error line.
+	w = httptest.NewRecorder()
+	h.Measure(w, streamRequest(ndjsonRows("a", "b", "fail")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected streaming to have started, got %d", w.Code)
+	}
+	lines = readStream(t, w.Body)
+	if len(lines) != 3 || lines[2].Error == nil || lines[2].Error.Status != http.StatusServiceUnavailable || lines[2].Summary != nil {
+		t.Errorf("Expected 2 results and a 503 error line, got %+v", lines)
+	}
+}
+
+func TestMeasureStream_ResultsBeforeInputEnds(t *testing.T) {
+	h := handler.NewMeasureHandler(&chunkEchoService{}, handler.WithStreamLimits(0, 0, 1))
+	srv := httptest.NewServer(http.HandlerFunc(h.Measure))
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, pr)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+			close(respCh)
+			return
+		}
+		respCh <- resp
+	}()
+
+	io.WriteString(pw, ndjsonRows("first"))
+	resp, ok := <-respCh
+	if !ok {
+		return
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.Contains(line, "first") {
+		t.Fatalf("Expected the first result while the request is still open, got %q (%v)", line, err)
+	}
+
+	io.WriteString(pw, ndjsonRows("second"))
+	pw.Close()
+	lines := readStream(t, reader)
+	if len(lines) != 2 || lines[0].PropertyName != "second" || lines[1].Summary == nil || lines[1].Summary.TotalRows != 2 {
+		t.Errorf("Unexpected remaining lines: %+v", lines)
+	}
+}
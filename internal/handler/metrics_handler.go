@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+)
+
+// MetricsWriter writes metrics in the Prometheus text exposition format.
+type MetricsWriter interface {
+	WriteMetrics(w io.Writer) error
+}
+
+// MetricsHandler serves metrics from every registered writer.
+type MetricsHandler struct {
+	writers []MetricsWriter
+}
+
+// NewMetricsHandler creates a new MetricsHandler.
+func NewMetricsHandler(writers ...MetricsWriter) *MetricsHandler {
+	return &MetricsHandler{writers: writers}
+}
+
+// Metrics handles the Prometheus scrape endpoint.
+func (h *MetricsHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, writer := range h.writers {
+		if err := writer.WriteMetrics(w); err != nil {
+			// Headers are already sent, so the scrape can only be cut short.
+			log.Printf("failed to write metrics: %v", err)
+			return
+		}
+	}
+}
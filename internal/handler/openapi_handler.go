@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPIHandler serves the OpenAPI document describing the HTTP API.
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+// NewOpenAPIHandler creates an OpenAPIHandler serving doc as JSON.
+func NewOpenAPIHandler(doc *openapi3.T) (*OpenAPIHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: spec}, nil
+}
+
+// Spec handles the OpenAPI document endpoint.
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.spec)
+}
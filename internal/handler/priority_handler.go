@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"emissions-cache-service/internal/policy"
+)
+
+// PriorityUsageReporter exposes how many priority slots each client holds.
+type PriorityUsageReporter interface {
+	Usage() []policy.ClientUsage
+}
+
+// PriorityHandler handles HTTP requests for priority policy reporting.
+type PriorityHandler struct {
+	reporter PriorityUsageReporter
+}
+
+// NewPriorityHandler creates a new PriorityHandler.
+func NewPriorityHandler(reporter PriorityUsageReporter) *PriorityHandler {
+	return &PriorityHandler{reporter: reporter}
+}
+
+// Usage handles the priority slot usage endpoint.
+func (h *PriorityHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, map[string][]policy.ClientUsage{"clients": h.reporter.Usage()})
+}
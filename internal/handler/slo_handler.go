@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"emissions-cache-service/internal/slo"
+)
+
+// SLOReporter exposes rolling latency SLO compliance.
+type SLOReporter interface {
+	Report() slo.Report
+}
+
+// SLOHandler handles HTTP requests for latency SLO reporting.
+type SLOHandler struct {
+	reporter SLOReporter
+}
+
+// NewSLOHandler creates a new SLOHandler.
+func NewSLOHandler(reporter SLOReporter) *SLOHandler {
+	return &SLOHandler{reporter: reporter}
+}
+
+// Report handles the SLO compliance endpoint.
+func (h *SLOHandler) Report(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, h.reporter.Report())
+}
@@ -0,0 +1,206 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key, so that a client retrying a submission after a network
+// failure gets the original response instead of repeating the work.
+package idempotency
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+)
+
+// sweepInterval is how often expired entries are discarded.
+const sweepInterval = time.Minute
+
+// inFlightRetryAfter is suggested to callers refused because every entry is
+// held by a request still in flight.
+const inFlightRetryAfter = time.Second
+
+// Response is a stored response, replayed for repeated requests.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// size approximates the memory held by a stored response.
+func (r *Response) size() int64 {
+	n := int64(len(r.Body))
+	for name, values := range r.Header {
+		n += int64(len(name))
+		for _, v := range values {
+			n += int64(len(v))
+		}
+	}
+	return n
+}
+
+// entry tracks one key. response is nil while the first request sent with
+// the key is being processed.
+type entry struct {
+	hash     string
+	response *Response
+	size     int64
+	expires  time.Time
+}
+
+// StoreOption defines a functional option for configuring the Store.
+type StoreOption func(*Store)
+
+// WithMaxBytes caps the approximate memory held by stored responses. The
+// oldest responses are discarded early to stay within it, and responses
+// larger than the cap are not stored.
+func WithMaxBytes(maxBytes int64) StoreOption {
+	return func(s *Store) {
+		s.maxBytes = maxBytes
+	}
+}
+
+// Store holds the outcome of each key for a fixed window. Keys are opaque to
+// the store; callers scope them, e.g. by client and route.
+type Store struct {
+	window     time.Duration
+	maxEntries int
+	maxBytes   int64
+
+	mu        sync.Mutex
+	now       func() time.Time
+	entries   map[string]*entry
+	bytes     int64
+	lastSweep time.Time
+}
+
+// NewStore creates a store keeping responses for window. When maxEntries is
+// positive, the oldest responses are discarded early to stay within it, and
+// new keys are refused while that many requests are in flight.
+func NewStore(window time.Duration, maxEntries int, opts ...StoreOption) *Store {
+	s := &Store{
+		window:     window,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*entry),
+		lastSweep:  time.Now(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Begin claims key for a request whose body hashes to hash. When key has
+// already completed for the same hash its stored response is returned for
+// replay. A nil response means the caller now owns key and must call Complete
+// or Release once the request has been handled. Reusing a key for a different
+// hash, or while its first request is in flight, is an error, as is a new key
+// while maxEntries requests are in flight.
+func (s *Store) Begin(key, hash string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		if e.hash != hash {
+			return nil, errors.NewIdempotencyKeyReusedError("Idempotency-Key was already used for a different request")
+		}
+		if e.response == nil {
+			return nil, errors.NewIdempotencyKeyInUseError("a request with this Idempotency-Key is still being processed")
+		}
+		return e.response, nil
+	}
+
+	// An expired entry not yet swept is replaced.
+	s.remove(key)
+	if s.maxEntries > 0 && len(s.entries) >= s.maxEntries && !s.evictOldest() {
+		return nil, errors.NewRateLimitedError("too many requests with an Idempotency-Key are in flight", inFlightRetryAfter)
+	}
+	s.entries[key] = &entry{hash: hash, expires: now.Add(s.window)}
+	return nil, nil
+}
+
+// Complete stores the response to the request that claimed key. The window
+// starts when the response is stored. A response too large for the byte cap
+// is not stored, releasing key instead.
+func (s *Store) Complete(key string, response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.response != nil {
+		return
+	}
+	// Drop any spare capacity of the caller's buffer.
+	response.Body = bytes.Clone(response.Body)
+	size := int64(len(key)+len(e.hash)) + response.size()
+	if s.maxBytes > 0 {
+		if size > s.maxBytes {
+			delete(s.entries, key)
+			return
+		}
+		// Only completed entries hold bytes, so there is one to evict.
+		for s.bytes+size > s.maxBytes {
+			s.evictOldest()
+		}
+	}
+	e.response = &response
+	e.size = size
+	e.expires = s.now().Add(s.window)
+	s.bytes += size
+}
+
+// Release gives up a claimed key without storing a response, so the request
+// may be retried with it.
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.response == nil {
+		delete(s.entries, key)
+	}
+}
+
+// sweep discards expired entries. Callers must hold mu.
+func (s *Store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			s.remove(key)
+		}
+	}
+}
+
+// remove discards the entry of key. Callers must hold mu.
+func (s *Store) remove(key string) {
+	if e, ok := s.entries[key]; ok {
+		s.bytes -= e.size
+		delete(s.entries, key)
+	}
+}
+
+// evictOldest discards the completed entry closest to expiry and reports
+// whether there was one. In-flight entries are kept so their requests cannot
+// be repeated. Callers must hold mu.
+func (s *Store) evictOldest() bool {
+	var oldest string
+	var oldestExpiry time.Time
+	for key, e := range s.entries {
+		if e.response == nil {
+			continue
+		}
+		if oldest == "" || e.expires.Before(oldestExpiry) {
+			oldest, oldestExpiry = key, e.expires
+		}
+	}
+	if oldest == "" {
+		return false
+	}
+	s.remove(oldest)
+	return true
+}
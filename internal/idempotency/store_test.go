@@ -0,0 +1,113 @@
+package idempotency_test
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/idempotency"
+)
+
+func TestStoreReplaysCompletedResponse(t *testing.T) {
+	store := idempotency.NewStore(time.Hour, 0)
+
+	if resp, err := store.Begin("key", "hash-a"); resp != nil || err != nil {
+		t.Fatalf("Expected the first request to claim the key, got %v, %v", resp, err)
+	}
+	_, err := store.Begin("key", "hash-a")
+	if !stderrors.Is(err, &errors.ServiceError{Type: errors.ErrorTypeIdempotencyKeyInUse}) {
+		t.Errorf("Expected an in-use error while the first request runs, got %v", err)
+	}
+
+	store.Complete("key", idempotency.Response{Status: http.StatusAccepted, Body: []byte("job")})
+	resp, err := store.Begin("key", "hash-a")
+	if err != nil || resp == nil || resp.Status != http.StatusAccepted || string(resp.Body) != "job" {
+		t.Fatalf("Expected the stored response, got %+v, %v", resp, err)
+	}
+
+	_, err = store.Begin("key", "hash-b")
+	if !stderrors.Is(err, &errors.ServiceError{Type: errors.ErrorTypeIdempotencyKeyReused}) {
+		t.Errorf("Expected a reuse error for a different body, got %v", err)
+	}
+}
+
+func TestStoreReleaseAllowsRetry(t *testing.T) {
+	store := idempotency.NewStore(time.Hour, 0)
+
+	store.Begin("key", "hash-a")
+	store.Release("key")
+	if resp, err := store.Begin("key", "hash-b"); resp != nil || err != nil {
+		t.Errorf("Expected a released key to be claimable again, got %v, %v", resp, err)
+	}
+}
+
+func TestStoreExpiresResponses(t *testing.T) {
+	store := idempotency.NewStore(20*time.Millisecond, 0)
+
+	store.Begin("key", "hash-a")
+	store.Complete("key", idempotency.Response{Status: http.StatusOK})
+	time.Sleep(30 * time.Millisecond)
+	if resp, err := store.Begin("key", "hash-b"); resp != nil || err != nil {
+		t.Errorf("Expected an expired key to be claimable again, got %v, %v", resp, err)
+	}
+}
+
+func TestStoreEvictsOldestResponse(t *testing.T) {
+	store := idempotency.NewStore(time.Hour, 2)
+
+	store.Begin("first", "hash")
+	store.Complete("first", idempotency.Response{Status: http.StatusOK})
+	store.Begin("second", "hash")
+	store.Complete("second", idempotency.Response{Status: http.StatusOK})
+	store.Begin("third", "hash")
+
+	if resp, _ := store.Begin("second", "hash"); resp == nil {
+		t.Error("Expected the newer response to be kept")
+	}
+	if resp, err := store.Begin("first", "other"); resp != nil || err != nil {
+		t.Errorf("Expected the oldest response to be evicted, got %v, %v", resp, err)
+	}
+}
+
+func TestStoreLimitsStoredBytes(t *testing.T) {
+	store := idempotency.NewStore(time.Hour, 0, idempotency.WithMaxBytes(64))
+	body := []byte(strings.Repeat("x", 40))
+
+	store.Begin("first", "hash")
+	store.Complete("first", idempotency.Response{Status: http.StatusOK, Body: body})
+	store.Begin("second", "hash")
+	store.Complete("second", idempotency.Response{Status: http.StatusOK, Body: body})
+
+	if resp, _ := store.Begin("second", "hash"); resp == nil {
+		t.Error("Expected the newer response to be kept")
+	}
+	if resp, err := store.Begin("first", "other"); resp != nil || err != nil {
+		t.Errorf("Expected the oldest response to be evicted for space, got %v, %v", resp, err)
+	}
+
+	// A response over the budget is not stored, so the key can be retried.
+	store.Begin("large", "hash")
+	store.Complete("large", idempotency.Response{Status: http.StatusOK, Body: []byte(strings.Repeat("x", 100))})
+	if resp, err := store.Begin("large", "hash"); resp != nil || err != nil {
+		t.Errorf("Expected an oversized response to be dropped, got %v, %v", resp, err)
+	}
+}
+
+func TestStoreLimitsRequestsInFlight(t *testing.T) {
+	store := idempotency.NewStore(time.Hour, 2)
+
+	store.Begin("first", "hash")
+	store.Begin("second", "hash")
+	_, err := store.Begin("third", "hash")
+	if !stderrors.Is(err, &errors.ServiceError{Type: errors.ErrorTypeRateLimited}) {
+		t.Errorf("Expected a rate limited error with every entry in flight, got %v", err)
+	}
+
+	store.Complete("first", idempotency.Response{Status: http.StatusOK})
+	if resp, err := store.Begin("third", "hash"); resp != nil || err != nil {
+		t.Errorf("Expected a completed entry to make room, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,420 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/service"
+	"emissions-cache-service/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Events sent to a job's callback URL when it finishes.
+const (
+	EventSucceeded = "job.succeeded"
+	EventFailed    = "job.failed"
+)
+
+// maxAttempts bounds how often a chunk is retried after transient failures.
+const maxAttempts = 5
+
+// MeasureService is the synchronous measurement used to process each chunk.
+type MeasureService interface {
+	GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error)
+}
+
+// Notifier delivers the summary of a finished job to its callback URL.
+type Notifier interface {
+	Send(ctx context.Context, jobID, callbackURL, event string, data interface{}) webhook.Delivery
+}
+
+// Job is a snapshot of a batch measurement job.
+type Job struct {
+	ID             string     `json:"id"`
+	Status         Status     `json:"status"`
+	ClientID       string     `json:"clientId,omitempty"`
+	Tenant         string     `json:"-"`
+	CallbackURL    string     `json:"callbackUrl,omitempty"`
+	TotalRows      int        `json:"totalRows"`
+	ProcessedRows  int        `json:"processedRows"`
+	TotalEmissions float64    `json:"totalEmissions"`
+	Warnings       []string   `json:"warnings,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Done reports whether the job has finished, successfully or not.
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// job is the mutable state behind a Job snapshot.
+type job struct {
+	Job
+	rows    []models.MeasureRow
+	results []models.MeasureRowResponse
+}
+
+// ManagerOption defines a functional option for configuring the Manager.
+type ManagerOption func(*Manager)
+
+// WithWorkers sets how many jobs are processed concurrently.
+func WithWorkers(n int) ManagerOption {
+	return func(m *Manager) {
+		if n > 0 {
+			m.workers = n
+		}
+	}
+}
+
+// WithChunkSize sets how many rows are sent to the measure service at once.
+// It should not exceed the per-client upstream row budget.
+func WithChunkSize(n int) ManagerOption {
+	return func(m *Manager) {
+		if n > 0 {
+			m.chunkSize = n
+		}
+	}
+}
+
+// WithMaxQueued caps how many jobs may wait for a worker.
+func WithMaxQueued(n int) ManagerOption {
+	return func(m *Manager) {
+		if n > 0 {
+			m.maxQueued = n
+		}
+	}
+}
+
+// WithMaxQueuedRows caps the rows of unfinished jobs, queued or running, so a
+// few large jobs cannot tie up the workers for long. Zero means unlimited.
+func WithMaxQueuedRows(n int) ManagerOption {
+	return func(m *Manager) {
+		if n >= 0 {
+			m.maxQueuedRows = n
+		}
+	}
+}
+
+// WithRetention sets how long finished jobs and their results are kept.
+func WithRetention(d time.Duration) ManagerOption {
+	return func(m *Manager) {
+		if d > 0 {
+			m.retention = d
+		}
+	}
+}
+
+// WithNotifier notifies the callback URL of each job, if any, when it finishes.
+func WithNotifier(n Notifier) ManagerOption {
+	return func(m *Manager) {
+		m.notifier = n
+	}
+}
+
+// SubmitOption defines a functional option for a single job submission.
+type SubmitOption func(*job)
+
+// WithCallbackURL has the job's summary sent to url when it finishes.
+func WithCallbackURL(url string) SubmitOption {
+	return func(j *job) {
+		j.CallbackURL = url
+	}
+}
+
+// Manager runs batch measurement jobs in the background, splitting each into
+// chunks processed through the measure service.
+type Manager struct {
+	service   MeasureService
+	workers   int
+	chunkSize int
+	maxQueued int
+	// maxQueuedRows caps queuedRows; zero means unlimited.
+	maxQueuedRows int
+	retention     time.Duration
+	notifier      Notifier
+	now           func() time.Time
+	sleep         func(ctx context.Context, d time.Duration) error
+
+	queue chan *job
+	mu    sync.Mutex
+	jobs  map[string]*job
+	// queuedRows counts the rows of unfinished jobs.
+	queuedRows int
+	// onFinish is called with the final snapshot of every job.
+	onFinish []func(Job)
+}
+
+// NewManager creates a Manager. Call Run to start processing.
+func NewManager(service MeasureService, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		service:       service,
+		workers:       2,
+		chunkSize:     500,
+		maxQueued:     100,
+		maxQueuedRows: 500000,
+		retention:     24 * time.Hour,
+		now:           time.Now,
+		sleep:         sleepContext,
+		jobs:          make(map[string]*job),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.queue = make(chan *job, m.maxQueued)
+	return m
+}
+
+// Run processes queued jobs until ctx is cancelled. Jobs interrupted by
+// cancellation are marked failed.
+func (m *Manager) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-m.queue:
+					m.process(ctx, j)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			m.prune()
+		}
+	}
+}
+
+// Submit queues rows for processing on behalf of the caller identified in ctx
+// and returns the new job.
+func (m *Manager) Submit(ctx context.Context, rows []models.MeasureRow, opts ...SubmitOption) (Job, error) {
+	if err := service.ValidateRows(rows); err != nil {
+		return Job{}, err
+	}
+
+	info := requestctx.FromContext(ctx)
+	j := &job{
+		Job: Job{
+			ID:        uuid.New().String(),
+			Status:    StatusQueued,
+			ClientID:  info.ClientID(),
+			Tenant:    info.Tenant(),
+			TotalRows: len(rows),
+			CreatedAt: m.now().UTC(),
+		},
+		rows:    rows,
+		results: make([]models.MeasureRowResponse, 0, len(rows)),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.maxQueuedRows > 0 && m.queuedRows+len(rows) > m.maxQueuedRows {
+		return Job{}, errors.NewRateLimitedError("too many rows queued, try again later", time.Minute)
+	}
+	select {
+	case m.queue <- j:
+	default:
+		return Job{}, errors.NewRateLimitedError("too many queued jobs, try again later", time.Minute)
+	}
+	m.queuedRows += len(rows)
+	m.jobs[j.ID] = j
+	return j.snapshot(), nil
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.snapshot(), true
+}
+
+// Results returns up to limit results of a job starting at offset, along with
+// the number of results available so far.
+func (m *Manager) Results(id string, offset, limit int) ([]models.MeasureRowResponse, int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, 0, false
+	}
+	total := len(j.results)
+	if offset >= total {
+		return []models.MeasureRowResponse{}, total, true
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return append([]models.MeasureRowResponse(nil), j.results[offset:end]...), total, true
+}
+
+// OnFinish registers fn to be called with the final snapshot of every job.
+// It must be called before Run.
+func (m *Manager) OnFinish(fn func(Job)) {
+	m.onFinish = append(m.onFinish, fn)
+}
+
+// process runs every chunk of j, stopping at the first chunk that fails.
+func (m *Manager) process(ctx context.Context, j *job) {
+	m.update(j, func() {
+		started := m.now().UTC()
+		j.Status = StatusRunning
+		j.StartedAt = &started
+	})
+
+	// The measure service attributes budgets, quotas and cache namespaces to
+	// the caller recorded in the request context.
+	jobCtx, info := requestctx.New(ctx, j.ID)
+	info.SetClientID(j.ClientID)
+	info.SetTenant(j.Tenant)
+
+	var failure error
+	for start := 0; start < len(j.rows); start += m.chunkSize {
+		end := start + m.chunkSize
+		if end > len(j.rows) {
+			end = len(j.rows)
+		}
+		resp, err := m.measure(jobCtx, j.rows[start:end])
+		if err != nil {
+			failure = fmt.Errorf("rows %d-%d: %w", start, end-1, err)
+			break
+		}
+		m.update(j, func() {
+			j.results = append(j.results, resp.Rows...)
+			j.ProcessedRows = end
+			j.TotalEmissions += resp.TotalEmissions
+			j.Warnings = append(j.Warnings, resp.Warnings...)
+		})
+	}
+
+	var final Job
+	m.update(j, func() {
+		finished := m.now().UTC()
+		j.FinishedAt = &finished
+		j.Status = StatusSucceeded
+		if failure != nil {
+			j.Status = StatusFailed
+			j.Error = failure.Error()
+		}
+		// Rows are no longer needed once processed.
+		m.queuedRows -= len(j.rows)
+		j.rows = nil
+		final = j.snapshot()
+	})
+	for _, fn := range m.onFinish {
+		fn(final)
+	}
+	if m.notifier != nil && final.CallbackURL != "" {
+		event := EventSucceeded
+		if final.Status == StatusFailed {
+			event = EventFailed
+		}
+		// Deliveries are retried in the background so slow receivers do
+		// not hold up the worker.
+		go m.notifier.Send(ctx, final.ID, final.CallbackURL, event, final)
+	}
+}
+
+// measure processes one chunk, waiting out rate limits and retrying
+// retryable upstream failures with exponential backoff.
+func (m *Manager) measure(ctx context.Context, rows []models.MeasureRow) (*models.MeasureResponse, error) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := m.service.GetMeasure(ctx, models.MeasureRequest{Rows: rows})
+		if err == nil {
+			return resp, nil
+		}
+		svcErr, ok := errors.As(err)
+		if !ok || attempt >= maxAttempts {
+			return nil, err
+		}
+		var wait time.Duration
+		switch {
+		case (svcErr.Type == errors.ErrorTypeRateLimited || svcErr.IsRetryable()) && svcErr.RetryAfter > 0:
+			wait = svcErr.RetryAfter
+		case svcErr.IsRetryable():
+			wait = backoff
+			backoff *= 2
+		default:
+			return nil, err
+		}
+		if err := m.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// update applies fn to j while holding the manager lock.
+func (m *Manager) update(j *job, fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn()
+}
+
+// prune drops finished jobs older than the retention period.
+func (m *Manager) prune() {
+	cutoff := m.now().Add(-m.retention)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, j := range m.jobs {
+		if j.FinishedAt != nil && j.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// snapshot copies the exported state of j. Callers must hold the manager lock.
+func (j *job) snapshot() Job {
+	s := j.Job
+	s.Warnings = append([]string(nil), j.Warnings...)
+	return s
+}
+
+// sleepContext waits for d or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
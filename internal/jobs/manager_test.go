@@ -0,0 +1,217 @@
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/jobs"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/webhook"
+)
+
+// chunkService records the chunks it is asked to measure and can fail the
+// first few calls with a given error.
+type chunkService struct {
+	mu       sync.Mutex
+	chunks   []int
+	clients  []string
+	failures int
+	err      error
+}
+
+func (s *chunkService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, s.err
+	}
+	s.chunks = append(s.chunks, len(req.Rows))
+	s.clients = append(s.clients, requestctx.FromContext(ctx).ClientID())
+	resp := &models.MeasureResponse{}
+	for _, row := range req.Rows {
+		resp.Rows = append(resp.Rows, models.MeasureRowResponse{PropertyName: row.InventoryID, TotalEmissions: 1})
+		resp.TotalEmissions++
+	}
+	return resp, nil
+}
+
+func testRows(n int) []models.MeasureRow {
+	rows := make([]models.MeasureRow, n)
+	for i := range rows {
+		rows[i] = models.MeasureRow{Country: "US", Channel: "web", Impressions: 1000, InventoryID: "inv"}
+	}
+	return rows
+}
+
+// runManager starts m and returns a channel receiving every finished job.
+func runManager(t *testing.T, m *jobs.Manager) <-chan jobs.Job {
+	t.Helper()
+	done := make(chan jobs.Job, 10)
+	m.OnFinish(func(j jobs.Job) { done <- j })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go m.Run(ctx)
+	return done
+}
+
+func waitJob(t *testing.T, done <-chan jobs.Job) jobs.Job {
+	t.Helper()
+	select {
+	case j := <-done:
+		return j
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not finish")
+		return jobs.Job{}
+	}
+}
+
+func TestManagerProcessesJobInChunks(t *testing.T) {
+	svc := &chunkService{}
+	m := jobs.NewManager(svc, jobs.WithChunkSize(2))
+	done := runManager(t, m)
+
+	ctx, info := requestctx.New(context.Background(), "req-1")
+	info.SetClientID("alice")
+	job, err := m.Submit(ctx, testRows(5))
+	if err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+	if job.Status != jobs.StatusQueued || job.TotalRows != 5 || job.ClientID != "alice" {
+		t.Fatalf("unexpected submitted job: %+v", job)
+	}
+
+	final := waitJob(t, done)
+	if final.Status != jobs.StatusSucceeded || final.ProcessedRows != 5 || final.TotalEmissions != 5 {
+		t.Fatalf("unexpected finished job: %+v", final)
+	}
+	if len(svc.chunks) != 3 || svc.chunks[0] != 2 || svc.chunks[2] != 1 {
+		t.Errorf("expected chunks of 2, 2 and 1 rows, got %v", svc.chunks)
+	}
+	for _, client := range svc.clients {
+		if client != "alice" {
+			t.Errorf("expected chunks to be measured as alice, got %q", client)
+		}
+	}
+
+	results, total, ok := m.Results(job.ID, 3, 10)
+	if !ok || total != 5 || len(results) != 2 {
+		t.Errorf("expected the last 2 of 5 results, got %d of %d (found %v)", len(results), total, ok)
+	}
+}
+
+func TestManagerRetriesRateLimitedChunks(t *testing.T) {
+	for _, limitErr := range []error{
+		errors.NewRateLimitedError("upstream budget exhausted", 10*time.Millisecond),
+		errors.NewUpstreamRateLimitedError("Scope3 API rate limit exceeded", 10*time.Millisecond, nil),
+	} {
+		svc := &chunkService{failures: 2, err: limitErr}
+		m := jobs.NewManager(svc)
+		done := runManager(t, m)
+
+		if _, err := m.Submit(context.Background(), testRows(3)); err != nil {
+			t.Fatalf("Submit returned error: %v", err)
+		}
+		if final := waitJob(t, done); final.Status != jobs.StatusSucceeded {
+			t.Fatalf("expected job to succeed after retries of %v, got %+v", limitErr, final)
+		}
+	}
+}
+
+func TestManagerFailsJobOnPermanentError(t *testing.T) {
+	for _, permanent := range []error{
+		errors.NewValidationError("bad row"),
+		errors.NewUpstreamValidationError("Scope3 API rejected the request (status: 400)", nil),
+	} {
+		svc := &chunkService{failures: 1, err: permanent}
+		m := jobs.NewManager(svc, jobs.WithChunkSize(2))
+		done := runManager(t, m)
+
+		if _, err := m.Submit(context.Background(), testRows(4)); err != nil {
+			t.Fatalf("Submit returned error: %v", err)
+		}
+		final := waitJob(t, done)
+		if final.Status != jobs.StatusFailed || final.Error == "" || final.ProcessedRows != 0 {
+			t.Fatalf("expected failed job with no processed rows for %v, got %+v", permanent, final)
+		}
+	}
+}
+
+func TestManagerSubmitValidatesAndLimitsQueue(t *testing.T) {
+	m := jobs.NewManager(&chunkService{}, jobs.WithMaxQueued(1))
+
+	if _, err := m.Submit(context.Background(), nil); err == nil {
+		t.Error("expected validation error for empty job")
+	}
+	if _, err := m.Submit(context.Background(), testRows(1)); err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+	_, err := m.Submit(context.Background(), testRows(1))
+	if svcErr, ok := err.(*errors.ServiceError); !ok || svcErr.Type != errors.ErrorTypeRateLimited {
+		t.Fatalf("expected rate limited error when the queue is full, got %v", err)
+	}
+}
+
+func TestManagerLimitsQueuedRows(t *testing.T) {
+	m := jobs.NewManager(&chunkService{}, jobs.WithMaxQueuedRows(10))
+
+	if _, err := m.Submit(context.Background(), testRows(8)); err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+	_, err := m.Submit(context.Background(), testRows(3))
+	if svcErr, ok := err.(*errors.ServiceError); !ok || svcErr.Type != errors.ErrorTypeRateLimited {
+		t.Fatalf("expected rate limited error past the row limit, got %v", err)
+	}
+	if _, err := m.Submit(context.Background(), testRows(2)); err != nil {
+		t.Fatalf("expected a job within the row limit to be queued, got %v", err)
+	}
+
+	// Finished jobs free their rows.
+	done := runManager(t, m)
+	waitJob(t, done)
+	waitJob(t, done)
+	if _, err := m.Submit(context.Background(), testRows(10)); err != nil {
+		t.Errorf("expected rows to be freed once jobs finish, got %v", err)
+	}
+}
+
+func TestManagerNotifiesCallbackURL(t *testing.T) {
+	received := make(chan webhook.Payload, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify([]byte("callback-secret"), r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var p webhook.Payload
+		json.Unmarshal(body, &p)
+		received <- p
+	}))
+	defer receiver.Close()
+
+	notifier := webhook.NewNotifier("callback-secret", webhook.WithPrivateAddresses())
+	m := jobs.NewManager(&chunkService{}, jobs.WithNotifier(notifier))
+	runManager(t, m)
+
+	job, err := m.Submit(context.Background(), testRows(2), jobs.WithCallbackURL(receiver.URL))
+	if err != nil {
+		t.Fatalf("Submit returned error: %v", err)
+	}
+	select {
+	case p := <-received:
+		data, _ := p.Data.(map[string]interface{})
+		if p.Event != jobs.EventSucceeded || data["id"] != job.ID || data["processedRows"] != float64(2) {
+			t.Errorf("unexpected notification: %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not notified")
+	}
+}
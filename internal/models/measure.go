@@ -1,10 +1,19 @@
 package models
 
+import "time"
+
 // MeasureRequest represents the public API request format.
 type MeasureRequest struct {
 	Rows []MeasureRow `json:"rows"`
 }
 
+// JobRequest represents a request to measure rows in a background job.
+type JobRequest struct {
+	Rows []MeasureRow `json:"rows"`
+	// CallbackURL receives a signed notification when the job finishes.
+	CallbackURL string `json:"callbackUrl,omitempty"`
+}
+
 // MeasureRow represents a single row in the public API request.
 type MeasureRow struct {
 	Country     string `json:"country"`
@@ -20,6 +29,7 @@ type MeasureResponse struct {
 	RequestID      string               `json:"requestId"`
 	TotalEmissions float64              `json:"totalEmissions"`
 	Rows           []MeasureRowResponse `json:"rows"`
+	Warnings       []string             `json:"warnings,omitempty"`
 }
 
 // MeasureRowResponse represents a single row in the public API response.
@@ -29,4 +39,8 @@ type MeasureRowResponse struct {
 	TotalEmissions    float64 `json:"totalEmissions,omitempty"`
 	Cached            bool    `json:"cached,omitempty"`
 	InventoryCoverage string  `json:"inventoryCoverage,omitempty"`
+	// ExpiresAt is when the cached entry for the row expires. It is omitted
+	// for priority entries, which never expire, and for rows that were not
+	// cached.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
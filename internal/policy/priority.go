@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"emissions-cache-service/internal/errors"
+)
+
+// Mode decides what happens to priority rows a client is not entitled to.
+type Mode string
+
+const (
+	// ModeReject fails the whole request.
+	ModeReject Mode = "reject"
+	// ModeDowngrade serves the rows but caches them with the default TTL.
+	ModeDowngrade Mode = "downgrade"
+)
+
+// Rule limits the priority entries a client may create.
+type Rule struct {
+	// MaxEntries caps the priority entries owned by the client; 0 means unlimited.
+	MaxEntries int
+	// AllowedInventoryIDs restricts priority to these inventory IDs; empty allows any.
+	AllowedInventoryIDs []string
+}
+
+// allows reports whether the rule permits pinning inventoryID.
+func (r Rule) allows(inventoryID string) bool {
+	if len(r.AllowedInventoryIDs) == 0 {
+		return true
+	}
+	for _, id := range r.AllowedInventoryIDs {
+		if id == inventoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// Candidate is a priority row a client asks to pin.
+type Candidate struct {
+	Key         string
+	InventoryID string
+}
+
+// EntryChecker reports whether a cache entry still exists, so slots for
+// entries that were deleted or flushed are released.
+type EntryChecker interface {
+	Contains(key string) bool
+}
+
+// ClientUsage reports how many priority slots a client holds.
+type ClientUsage struct {
+	ClientID   string `json:"clientId"`
+	Used       int    `json:"used"`
+	MaxEntries int    `json:"maxEntries"`
+}
+
+// PriorityPolicy limits which clients may create never-expiring cache entries
+// and how many each may hold.
+type PriorityPolicy struct {
+	mode     Mode
+	defaults Rule
+	clients  map[string]Rule
+	entries  EntryChecker
+
+	mu    sync.Mutex
+	slots map[string]map[string]struct{} // client -> owned keys
+	owner map[string]string              // key -> client
+}
+
+// NewPriorityPolicy creates a policy applying clients' rules, falling back to
+// defaults for clients without their own.
+func NewPriorityPolicy(mode Mode, defaults Rule, clients map[string]Rule, entries EntryChecker) *PriorityPolicy {
+	return &PriorityPolicy{
+		mode:     mode,
+		defaults: defaults,
+		clients:  clients,
+		entries:  entries,
+		slots:    make(map[string]map[string]struct{}),
+		owner:    make(map[string]string),
+	}
+}
+
+// rule returns the rule that applies to client.
+func (p *PriorityPolicy) rule(client string) Rule {
+	if r, ok := p.clients[client]; ok {
+		return r
+	}
+	return p.defaults
+}
+
+// Admit decides which candidates client may pin. In reject mode any violation
+// fails with a forbidden error; in downgrade mode the offending candidates are
+// reported as not allowed, with a reason for each.
+func (p *PriorityPolicy) Admit(client string, candidates []Candidate) (allowed []bool, reasons []string, err error) {
+	rule := p.rule(client)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	owned := p.ownedLocked(client)
+
+	allowed = make([]bool, len(candidates))
+	newKeys := make(map[string]struct{})
+	for i, c := range candidates {
+		var reason string
+		_, alreadyOwned := owned[c.Key]
+		_, alreadyCounted := newKeys[c.Key]
+		switch {
+		case !rule.allows(c.InventoryID):
+			reason = fmt.Sprintf("inventoryId %s is not allowlisted for priority caching", c.InventoryID)
+		case alreadyOwned || alreadyCounted:
+		case rule.MaxEntries > 0 && len(owned)+len(newKeys) >= rule.MaxEntries:
+			reason = fmt.Sprintf("priority quota of %d entries exhausted", rule.MaxEntries)
+		default:
+			newKeys[c.Key] = struct{}{}
+		}
+		if reason == "" {
+			allowed[i] = true
+			continue
+		}
+		if p.mode != ModeDowngrade {
+			return nil, nil, errors.NewForbiddenError(reason)
+		}
+		reasons = append(reasons, reason)
+	}
+	return allowed, reasons, nil
+}
+
+// Reserve records that client now owns the priority entry key. It returns
+// false if the client's quota filled up since Admit, in which case the entry
+// should be cached without priority.
+func (p *PriorityPolicy) Reserve(client, key string) bool {
+	rule := p.rule(client)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if current, ok := p.owner[key]; ok && current != client && p.entries.Contains(key) {
+		// Another client already pinned this entry; rewriting it keeps their slot.
+		return true
+	}
+	owned := p.ownedLocked(client)
+	if _, ok := owned[key]; !ok && rule.MaxEntries > 0 && len(owned) >= rule.MaxEntries {
+		return false
+	}
+	if owned == nil {
+		owned = make(map[string]struct{})
+		p.slots[client] = owned
+	}
+	owned[key] = struct{}{}
+	p.owner[key] = client
+	return true
+}
+
+// Usage reports the priority slots held by every client with at least one.
+func (p *PriorityPolicy) Usage() []ClientUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := make([]ClientUsage, 0, len(p.slots))
+	for client := range p.slots {
+		owned := p.ownedLocked(client)
+		if len(owned) == 0 {
+			continue
+		}
+		usage = append(usage, ClientUsage{
+			ClientID:   client,
+			Used:       len(owned),
+			MaxEntries: p.rule(client).MaxEntries,
+		})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].ClientID < usage[j].ClientID })
+	return usage
+}
+
+// ownedLocked returns the keys client still holds, releasing slots whose
+// entries have left the cache. Callers must hold mu.
+func (p *PriorityPolicy) ownedLocked(client string) map[string]struct{} {
+	owned := p.slots[client]
+	for key := range owned {
+		if !p.entries.Contains(key) {
+			delete(owned, key)
+			delete(p.owner, key)
+		}
+	}
+	if owned != nil && len(owned) == 0 {
+		delete(p.slots, client)
+		return nil
+	}
+	return owned
+}
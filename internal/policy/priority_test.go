@@ -0,0 +1,84 @@
+package policy_test
+
+import (
+	"testing"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/policy"
+)
+
+type fakeEntries map[string]bool
+
+func (f fakeEntries) Contains(key string) bool { return f[key] }
+
+func TestAdmitRejectsDisallowedInventory(t *testing.T) {
+	p := policy.NewPriorityPolicy(policy.ModeReject, policy.Rule{AllowedInventoryIDs: []string{"inv-1"}}, nil, fakeEntries{})
+
+	if _, _, err := p.Admit("client-a", []policy.Candidate{{Key: "k1", InventoryID: "inv-1"}}); err != nil {
+		t.Fatalf("Expected allowlisted inventory to be admitted, got %v", err)
+	}
+	_, _, err := p.Admit("client-a", []policy.Candidate{{Key: "k2", InventoryID: "inv-2"}})
+	svcErr, ok := err.(*errors.ServiceError)
+	if !ok || svcErr.Type != errors.ErrorTypeForbidden {
+		t.Fatalf("Expected forbidden error, got %v", err)
+	}
+}
+
+func TestAdmitDowngradesOverQuota(t *testing.T) {
+	p := policy.NewPriorityPolicy(policy.ModeDowngrade, policy.Rule{MaxEntries: 2}, nil, fakeEntries{})
+
+	allowed, reasons, err := p.Admit("client-a", []policy.Candidate{
+		{Key: "k1", InventoryID: "inv-1"},
+		{Key: "k1", InventoryID: "inv-1"},
+		{Key: "k2", InventoryID: "inv-2"},
+		{Key: "k3", InventoryID: "inv-3"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error in downgrade mode, got %v", err)
+	}
+	want := []bool{true, true, true, false}
+	for i := range want {
+		if allowed[i] != want[i] {
+			t.Errorf("Candidate %d: expected allowed=%v, got %v", i, want[i], allowed[i])
+		}
+	}
+	if len(reasons) != 1 {
+		t.Errorf("Expected 1 reason, got %v", reasons)
+	}
+}
+
+func TestPerClientRuleOverridesDefaults(t *testing.T) {
+	clients := map[string]policy.Rule{"dashboard": {MaxEntries: 0}}
+	p := policy.NewPriorityPolicy(policy.ModeReject, policy.Rule{MaxEntries: 1}, clients, fakeEntries{})
+
+	candidates := []policy.Candidate{{Key: "k1"}, {Key: "k2"}}
+	if _, _, err := p.Admit("dashboard", candidates); err != nil {
+		t.Errorf("Expected unlimited client to be admitted, got %v", err)
+	}
+	if _, _, err := p.Admit("other", candidates); err == nil {
+		t.Error("Expected default quota to reject the second entry")
+	}
+}
+
+func TestReserveReleasesRemovedEntries(t *testing.T) {
+	entries := fakeEntries{}
+	p := policy.NewPriorityPolicy(policy.ModeReject, policy.Rule{MaxEntries: 1}, nil, entries)
+
+	if !p.Reserve("client-a", "k1") {
+		t.Fatal("Expected first reservation to succeed")
+	}
+	entries["k1"] = true
+	if p.Reserve("client-a", "k2") {
+		t.Fatal("Expected reservation beyond quota to fail")
+	}
+	usage := p.Usage()
+	if len(usage) != 1 || usage[0].ClientID != "client-a" || usage[0].Used != 1 || usage[0].MaxEntries != 1 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	// Deleting the entry frees the slot.
+	delete(entries, "k1")
+	if !p.Reserve("client-a", "k2") {
+		t.Error("Expected reservation to succeed once the old entry left the cache")
+	}
+}
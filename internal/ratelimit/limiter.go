@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are discarded.
+const sweepInterval = time.Minute
+
+// bucket is a token bucket for a single key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter enforces a token bucket per key, e.g. per client identity. Each key
+// may spend up to burst tokens at once, refilled at rate tokens per second.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter creates a limiter refilling rate tokens per second up to burst.
+// Callers should pass a positive rate; with none, the burst is never refilled.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if rate < 0 {
+		rate = 0
+	}
+	return &Limiter{
+		rate:      rate,
+		burst:     float64(burst),
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Burst returns the largest number of tokens a single call can spend.
+func (l *Limiter) Burst() int {
+	return int(l.burst)
+}
+
+// AllowN spends n tokens from key's bucket if available. Otherwise nothing is
+// spent and the time until n tokens will be available is returned; a request
+// larger than the burst can never succeed and reports ok=false with a zero
+// wait.
+func (l *Limiter) AllowN(key string, n int) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	need := float64(n)
+	if need > l.burst {
+		return false, 0
+	}
+	if b.tokens >= need {
+		b.tokens -= need
+		return true, 0
+	}
+	// A bucket that never refills cannot say when tokens will be available.
+	if l.rate <= 0 {
+		return false, 0
+	}
+	wait := time.Duration((need - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Allow spends a single token from key's bucket.
+func (l *Limiter) Allow(key string) (ok bool, retryAfter time.Duration) {
+	return l.AllowN(key, 1)
+}
+
+// sweep discards buckets that have refilled completely, since a fresh bucket
+// is equivalent. Callers must hold mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
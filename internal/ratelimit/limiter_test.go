@@ -0,0 +1,68 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/ratelimit"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1, 2)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("client-a"); !ok {
+			t.Fatalf("Expected request %d within burst to be allowed", i)
+		}
+	}
+	ok, retryAfter := limiter.Allow("client-a")
+	if ok {
+		t.Fatal("Expected request beyond burst to be limited")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected retry after at most 1s, got %v", retryAfter)
+	}
+
+	if ok, _ := limiter.Allow("client-b"); !ok {
+		t.Error("Expected other clients to have their own bucket")
+	}
+}
+
+func TestLimiterRefills(t *testing.T) {
+	limiter := ratelimit.NewLimiter(100, 1)
+
+	if ok, _ := limiter.Allow("client"); !ok {
+		t.Fatal("Expected first request to be allowed")
+	}
+	if ok, _ := limiter.Allow("client"); ok {
+		t.Fatal("Expected second request to be limited")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := limiter.Allow("client"); !ok {
+		t.Error("Expected bucket to refill")
+	}
+}
+
+func TestLimiterAllowNBeyondBurst(t *testing.T) {
+	limiter := ratelimit.NewLimiter(10, 5)
+
+	ok, retryAfter := limiter.AllowN("client", 6)
+	if ok || retryAfter != 0 {
+		t.Errorf("Expected request larger than burst to fail without retry, got ok=%v retryAfter=%v", ok, retryAfter)
+	}
+	if ok, _ := limiter.AllowN("client", 5); !ok {
+		t.Error("Expected failed oversized request not to spend tokens")
+	}
+}
+
+func TestLimiterWithoutRefill(t *testing.T) {
+	limiter := ratelimit.NewLimiter(0, 1)
+
+	if ok, _ := limiter.Allow("client-a"); !ok {
+		t.Fatal("Expected the burst to be available")
+	}
+	ok, retryAfter := limiter.Allow("client-a")
+	if ok || retryAfter != 0 {
+		t.Errorf("Expected a limit without a retry hint, got ok=%v retryAfter=%v", ok, retryAfter)
+	}
+}
@@ -1,24 +1,68 @@
 package cache
 
 import (
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 )
 
+// CacheOption defines a functional option for configuring the cache.
+type CacheOption func(*EmissionsCache)
+
+// WithTenantLimits overrides the default TTL and caps the number of entries for
+// the given tenants.
+func WithTenantLimits(limits map[string]TenantLimits) CacheOption {
+	return func(ec *EmissionsCache) {
+		ec.tenants.limits = limits
+	}
+}
+
 // EmissionsCache is a concurrency-safe in-memory cache for emissions data.
 // NOTE: This implementation uses an in-memory cache which is suitable for single-instance deployments.
 // For a distributed system, replacing this with a Redis-backed cache.
 type EmissionsCache struct {
 	store *cache.Cache
+	stats *statsRecorder
+	// deleting marks keys removed through Delete so the eviction callback does
+	// not count them as expirations. deleteMu serializes removals so each
+	// marker belongs to one of them.
+	deleting sync.Map
+	deleteMu sync.Mutex
+	// tenants tracks which entries belong to which tenant.
+	tenants *tenantIndex
+	// sizes tracks the approximate memory footprint of each entry.
+	sizes *sizeIndex
 }
 
 // NewInMemoryCache creates a new in-memory cache with a default TTL and cleanup interval.
-func NewInMemoryCache(defaultTTL, cleanupInterval time.Duration, _ int) *EmissionsCache {
+func NewInMemoryCache(defaultTTL, cleanupInterval time.Duration, _ int, opts ...CacheOption) *EmissionsCache {
 	c := cache.New(defaultTTL, cleanupInterval)
-	return &EmissionsCache{
-		store: c,
+	ec := &EmissionsCache{
+		store:   c,
+		stats:   newStatsRecorder(),
+		tenants: newTenantIndex(),
+		sizes:   newSizeIndex(),
+	}
+	for _, opt := range opts {
+		opt(ec)
 	}
+	c.OnEvicted(func(key string, _ interface{}) {
+		ec.sizes.remove(key)
+		tenantStats := ec.tenants.untrack(key)
+		if _, deleted := ec.deleting.LoadAndDelete(key); deleted {
+			ec.stats.forget(key)
+			if tenantStats != nil {
+				tenantStats.forget(key)
+			}
+			return
+		}
+		ec.stats.recordExpiration(key)
+		if tenantStats != nil {
+			tenantStats.recordExpiration(key)
+		}
+	})
+	return ec
 }
 
 // Set stores a value in the cache.
@@ -29,9 +73,75 @@ func (ec *EmissionsCache) Set(key string, value interface{}, isPriority bool) {
 		ttl = cache.NoExpiration
 	}
 	ec.store.Set(key, value, ttl)
+	ec.sizes.set(key, value)
 }
 
 // Get retrieves a value from the cache.
 func (ec *EmissionsCache) Get(key string) (interface{}, bool) {
-	return ec.store.Get(key)
+	value, found := ec.store.Get(key)
+	if found {
+		ec.stats.recordHit(key)
+	} else {
+		ec.stats.recordMiss()
+	}
+	return value, found
+}
+
+// Contains reports whether key is cached without counting as a lookup in the
+// hit/miss statistics.
+func (ec *EmissionsCache) Contains(key string) bool {
+	_, found := ec.store.Get(key)
+	return found
+}
+
+// Expiration returns when key expires and whether it is cached. Priority
+// entries never expire and report the zero time.
+func (ec *EmissionsCache) Expiration(key string) (time.Time, bool) {
+	_, expiration, found := ec.store.GetWithExpiration(key)
+	return expiration, found
+}
+
+// Delete removes a single entry and reports whether it was present.
+func (ec *EmissionsCache) Delete(key string) bool {
+	ec.deleteMu.Lock()
+	defer ec.deleteMu.Unlock()
+	if !ec.Contains(key) {
+		return false
+	}
+	return ec.remove(key)
+}
+
+// remove deletes key, marking it so the eviction callback records a deletion
+// rather than an expiration, and reports whether this call removed it. The
+// callback consumes the marker; if it is left, the janitor expired the entry
+// first and the stale marker is dropped so a later expiry is not mistaken
+// for a deletion. Callers must hold deleteMu.
+func (ec *EmissionsCache) remove(key string) bool {
+	ec.deleting.Store(key, struct{}{})
+	ec.store.Delete(key)
+	_, stale := ec.deleting.LoadAndDelete(key)
+	return !stale
+}
+
+// Flush removes every entry, including priority entries, and returns how many
+// were removed.
+func (ec *EmissionsCache) Flush() int {
+	n := ec.store.ItemCount()
+	ec.store.Flush()
+	ec.sizes.reset()
+	ec.stats.forgetAll()
+	ec.tenants.reset()
+	return n
+}
+
+// Stats returns a snapshot of cache usage, including at most topN of the most
+// frequently hit keys. Keys owned by a tenant are only reported by TenantStats.
+func (ec *EmissionsCache) Stats(topN int) Stats {
+	var stats Stats
+	for key, item := range ec.store.Items() {
+		// Items already skips expired entries; a zero expiration marks priority.
+		stats.addEntry(item.Expiration == 0, ec.sizes.get(key))
+	}
+	ec.stats.snapshot(&stats, topN, ec.tenants.owned)
+	return stats
 }
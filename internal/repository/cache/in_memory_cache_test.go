@@ -1,6 +1,9 @@
 package cache_test
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -84,3 +87,129 @@ func TestCacheTTLVariants(t *testing.T) {
 		t.Errorf("Expected key 'permanent' to persist")
 	}
 }
+
+func TestCacheStats(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(time.Minute, time.Minute, 0)
+
+	cacheRepo.Set("standard", "value", false)
+	cacheRepo.Set("priority", "value", true)
+	cacheRepo.Set("hot", "value", false)
+
+	for i := 0; i < 3; i++ {
+		cacheRepo.Get("hot")
+	}
+	cacheRepo.Get("priority")
+	cacheRepo.Get("missing")
+
+	stats := cacheRepo.Stats(1)
+	if stats.Entries != 3 || stats.PriorityEntries != 1 || stats.StandardEntries != 2 {
+		t.Errorf("Expected 3 entries (1 priority, 2 standard), got %+v", stats)
+	}
+	if stats.Hits != 4 || stats.Misses != 1 {
+		t.Errorf("Expected 4 hits and 1 miss, got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+	if stats.HitRatio != 0.8 {
+		t.Errorf("Expected hit ratio 0.8, got %v", stats.HitRatio)
+	}
+	if len(stats.Windows) != 3 {
+		t.Fatalf("Expected 3 sliding windows, got %d", len(stats.Windows))
+	}
+	for _, w := range stats.Windows {
+		if w.Hits != 4 || w.Misses != 1 {
+			t.Errorf("Expected window %s to see 4 hits and 1 miss, got %+v", w.Window, w)
+		}
+	}
+	if len(stats.TopKeys) != 1 || stats.TopKeys[0].Key != "hot" || stats.TopKeys[0].Hits != 3 {
+		t.Errorf("Expected top key 'hot' with 3 hits, got %+v", stats.TopKeys)
+	}
+	if stats.ApproxMemoryBytes <= 0 {
+		t.Errorf("Expected positive memory estimate, got %d", stats.ApproxMemoryBytes)
+	}
+
+	// The estimate follows entries as they are removed.
+	cacheRepo.Delete("hot")
+	if after := cacheRepo.Stats(1).ApproxMemoryBytes; after <= 0 || after >= stats.ApproxMemoryBytes {
+		t.Errorf("Expected the estimate to shrink from %d after a delete, got %d", stats.ApproxMemoryBytes, after)
+	}
+}
+
+func TestCacheStatsExpirations(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(500*time.Millisecond, 250*time.Millisecond, 0)
+
+	cacheRepo.Set("short-lived", "value", false)
+	cacheRepo.Get("short-lived")
+
+	time.Sleep(time.Second)
+
+	stats := cacheRepo.Stats(10)
+	if stats.Expirations != 1 {
+		t.Errorf("Expected 1 expiration, got %d", stats.Expirations)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("Expected no entries, got %d", stats.Entries)
+	}
+	if len(stats.TopKeys) != 0 {
+		t.Errorf("Expected expired key to be dropped from top keys, got %+v", stats.TopKeys)
+	}
+}
+
+func TestCacheDeleteAndFlush(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(time.Minute, time.Minute, 0)
+	cacheRepo.Set("a", "value", false)
+	cacheRepo.Set("b", "value", true)
+	cacheRepo.Set("c", "value", false)
+
+	if !cacheRepo.Delete("a") {
+		t.Errorf("Expected Delete to report an existing key")
+	}
+	if cacheRepo.Delete("a") {
+		t.Errorf("Expected Delete to report a missing key")
+	}
+	if _, found := cacheRepo.Get("a"); found {
+		t.Errorf("Expected key 'a' to be deleted")
+	}
+	if stats := cacheRepo.Stats(10); stats.Expirations != 0 {
+		t.Errorf("Expected deletions not to count as expirations, got %d", stats.Expirations)
+	}
+
+	if removed := cacheRepo.Flush(); removed != 2 {
+		t.Errorf("Expected Flush to remove 2 entries, got %d", removed)
+	}
+	if _, found := cacheRepo.Get("b"); found {
+		t.Errorf("Expected priority key 'b' to be flushed")
+	}
+}
+
+func TestCacheConcurrentDeletesLeaveNoMarkers(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(500*time.Millisecond, 250*time.Millisecond, 0)
+	const keys = 50
+	for i := 0; i < keys; i++ {
+		cacheRepo.Set(fmt.Sprintf("k%d", i), "value", false)
+	}
+
+	// Every key is removed by exactly one of the racing deletes.
+	var removed int32
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				if cacheRepo.Delete(fmt.Sprintf("k%d", i)) {
+					atomic.AddInt32(&removed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if removed != keys {
+		t.Errorf("Expected %d deletions, got %d", keys, removed)
+	}
+
+	// A later natural expiry of a deleted key still counts as an expiration.
+	cacheRepo.Set("k0", "value", false)
+	time.Sleep(time.Second)
+	if got := cacheRepo.Stats(0).Expirations; got != 1 {
+		t.Errorf("Expected 1 expiration, got %d", got)
+	}
+}
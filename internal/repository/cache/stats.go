@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// entryOverheadBytes is a rough per-entry allowance for the go-cache item, map
+// bucket and interface headers, used when approximating memory usage.
+const entryOverheadBytes = 96
+
+// statsWindows are the sliding windows reported for hit/miss ratios.
+var statsWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// Stats is a point-in-time snapshot of cache behaviour.
+type Stats struct {
+	Entries           int           `json:"entries"`
+	PriorityEntries   int           `json:"priorityEntries"`
+	StandardEntries   int           `json:"standardEntries"`
+	Hits              uint64        `json:"hits"`
+	Misses            uint64        `json:"misses"`
+	HitRatio          float64       `json:"hitRatio"`
+	Windows           []WindowStats `json:"windows"`
+	TopKeys           []KeyStats    `json:"topKeys"`
+	Expirations       uint64        `json:"expirations"`
+	ApproxMemoryBytes int64         `json:"approxMemoryBytes"`
+}
+
+// addEntry counts one live entry of approximately size bytes towards the
+// entry and memory totals.
+func (s *Stats) addEntry(isPriority bool, size int64) {
+	if isPriority {
+		s.PriorityEntries++
+	} else {
+		s.StandardEntries++
+	}
+	s.Entries++
+	s.ApproxMemoryBytes += size
+}
+
+// sizeIndex keeps the approximate size of every entry, computed once when it
+// is stored, so stats never serialize the cached values.
+type sizeIndex struct {
+	mu    sync.Mutex
+	sizes map[string]int64
+}
+
+// newSizeIndex creates an empty index.
+func newSizeIndex() *sizeIndex {
+	return &sizeIndex{sizes: make(map[string]int64)}
+}
+
+// set records the size of value stored under key, replacing any earlier one.
+func (s *sizeIndex) set(key string, value interface{}) {
+	size := int64(len(key)) + approxSize(value) + entryOverheadBytes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizes[key] = size
+}
+
+// get returns the recorded size of key.
+func (s *sizeIndex) get(key string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sizes[key]
+}
+
+// remove forgets key.
+func (s *sizeIndex) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sizes, key)
+}
+
+// reset forgets every key.
+func (s *sizeIndex) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizes = make(map[string]int64)
+}
+
+// WindowStats reports hits and misses observed over a sliding window.
+type WindowStats struct {
+	Window   string  `json:"window"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// KeyStats reports how often a single key has been served from the cache.
+type KeyStats struct {
+	Key  string `json:"key"`
+	Hits uint64 `json:"hits"`
+}
+
+// bucket holds the hit/miss counts for one second of the sliding window.
+type bucket struct {
+	second int64
+	hits   uint64
+	misses uint64
+}
+
+// statsRecorder tracks lookups and evictions for an EmissionsCache.
+type statsRecorder struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	buckets     []bucket
+	hits        uint64
+	misses      uint64
+	expirations uint64
+	keyHits     map[string]uint64
+}
+
+// newStatsRecorder creates a recorder able to answer for the longest window.
+func newStatsRecorder() *statsRecorder {
+	longest := statsWindows[len(statsWindows)-1]
+	return &statsRecorder{
+		now:     time.Now,
+		buckets: make([]bucket, int(longest/time.Second)),
+		keyHits: make(map[string]uint64),
+	}
+}
+
+// currentBucket returns the bucket for the current second, resetting it if it
+// still holds counts from a previous lap of the ring. Callers must hold mu.
+func (s *statsRecorder) currentBucket() *bucket {
+	sec := s.now().Unix()
+	b := &s.buckets[sec%int64(len(s.buckets))]
+	if b.second != sec {
+		*b = bucket{second: sec}
+	}
+	return b
+}
+
+// recordHit counts a successful lookup of key.
+func (s *statsRecorder) recordHit(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits++
+	s.keyHits[key]++
+	s.currentBucket().hits++
+}
+
+// recordMiss counts a failed lookup.
+func (s *statsRecorder) recordMiss() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.misses++
+	s.currentBucket().misses++
+}
+
+// recordExpiration counts an entry removed because its TTL elapsed.
+func (s *statsRecorder) recordExpiration(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expirations++
+	delete(s.keyHits, key)
+}
+
+// forget drops per-key counters for a key that is no longer cached.
+func (s *statsRecorder) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keyHits, key)
+}
+
+// forgetAll drops per-key counters for every key.
+func (s *statsRecorder) forgetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyHits = make(map[string]uint64)
+}
+
+// snapshot fills the counter-derived fields of a Stats value. Keys for which
+// hidden, if non-nil, returns true are left out of TopKeys.
+func (s *statsRecorder) snapshot(stats *Stats, topN int, hidden func(key string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats.Hits = s.hits
+	stats.Misses = s.misses
+	stats.HitRatio = ratio(s.hits, s.misses)
+	stats.Expirations = s.expirations
+
+	nowSec := s.now().Unix()
+	for _, window := range statsWindows {
+		since := nowSec - int64(window/time.Second)
+		ws := WindowStats{Window: window.String()}
+		for _, b := range s.buckets {
+			if b.second > since && b.second <= nowSec {
+				ws.Hits += b.hits
+				ws.Misses += b.misses
+			}
+		}
+		ws.HitRatio = ratio(ws.Hits, ws.Misses)
+		stats.Windows = append(stats.Windows, ws)
+	}
+
+	stats.TopKeys = make([]KeyStats, 0, len(s.keyHits))
+	for key, hits := range s.keyHits {
+		if hidden != nil && hidden(key) {
+			continue
+		}
+		stats.TopKeys = append(stats.TopKeys, KeyStats{Key: key, Hits: hits})
+	}
+	sort.Slice(stats.TopKeys, func(i, j int) bool {
+		if stats.TopKeys[i].Hits != stats.TopKeys[j].Hits {
+			return stats.TopKeys[i].Hits > stats.TopKeys[j].Hits
+		}
+		return stats.TopKeys[i].Key < stats.TopKeys[j].Key
+	})
+	if topN >= 0 && len(stats.TopKeys) > topN {
+		stats.TopKeys = stats.TopKeys[:topN]
+	}
+}
+
+// ratio returns hits / (hits + misses), or zero when nothing was looked up.
+func ratio(hits, misses uint64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// approxSize estimates the in-memory footprint of a cached value.
+func approxSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(b))
+}
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// TenantLimits overrides cache behaviour for one tenant.
+type TenantLimits struct {
+	// DefaultTTL replaces the cache-wide TTL of the tenant's standard entries;
+	// zero keeps the cache-wide TTL.
+	DefaultTTL time.Duration
+	// MaxEntries caps the tenant's entries; zero means unlimited.
+	MaxEntries int
+}
+
+// tenantIndex records which tenant owns each entry, along with per-tenant
+// hit/miss statistics.
+type tenantIndex struct {
+	limits map[string]TenantLimits
+
+	// admit serializes the limit check, eviction and insert of SetForTenant
+	// for limited tenants. It is separate from mu, which the eviction
+	// callback takes.
+	admit sync.Mutex
+
+	mu     sync.Mutex
+	keys   map[string]map[string]struct{} // tenant -> keys
+	owners map[string]string              // key -> tenant
+	stats  map[string]*statsRecorder
+}
+
+// newTenantIndex creates an empty index.
+func newTenantIndex() *tenantIndex {
+	return &tenantIndex{
+		keys:   make(map[string]map[string]struct{}),
+		owners: make(map[string]string),
+		stats:  make(map[string]*statsRecorder),
+	}
+}
+
+// recorder returns the statistics of tenant, creating them on first use.
+func (t *tenantIndex) recorder(tenant string) *statsRecorder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.stats[tenant]
+	if !ok {
+		r = newStatsRecorder()
+		t.stats[tenant] = r
+	}
+	return r
+}
+
+// track records that tenant owns key.
+func (t *tenantIndex) track(tenant, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys, ok := t.keys[tenant]
+	if !ok {
+		keys = make(map[string]struct{})
+		t.keys[tenant] = keys
+	}
+	keys[key] = struct{}{}
+	t.owners[key] = tenant
+}
+
+// untrack forgets the owner of key, returning the owner's statistics, or nil
+// if key belongs to no tenant.
+func (t *tenantIndex) untrack(key string) *statsRecorder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tenant, ok := t.owners[key]
+	if !ok {
+		return nil
+	}
+	delete(t.owners, key)
+	delete(t.keys[tenant], key)
+	return t.stats[tenant]
+}
+
+// tenantKeys returns a copy of the keys owned by tenant.
+func (t *tenantIndex) tenantKeys(tenant string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]string, 0, len(t.keys[tenant]))
+	for key := range t.keys[tenant] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// owns reports whether tenant already owns key.
+func (t *tenantIndex) owns(tenant, key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.keys[tenant][key]
+	return ok
+}
+
+// owned reports whether any tenant owns key.
+func (t *tenantIndex) owned(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.owners[key]
+	return ok
+}
+
+// reset forgets every owner and the per-key statistics of every tenant.
+func (t *tenantIndex) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = make(map[string]map[string]struct{})
+	t.owners = make(map[string]string)
+	for _, r := range t.stats {
+		r.forgetAll()
+	}
+}
+
+// GetForTenant retrieves a value on behalf of tenant, counting the lookup in
+// both the cache-wide and the tenant's statistics. The key must already be
+// namespaced by the caller.
+func (ec *EmissionsCache) GetForTenant(tenant, key string) (interface{}, bool) {
+	if tenant == "" {
+		return ec.Get(key)
+	}
+	value, found := ec.Get(key)
+	if found {
+		ec.tenants.recorder(tenant).recordHit(key)
+	} else {
+		ec.tenants.recorder(tenant).recordMiss()
+	}
+	return value, found
+}
+
+// SetForTenant stores a value owned by tenant, applying the tenant's default
+// TTL and entry limit. When the tenant is at its limit, its standard entry
+// closest to expiry is evicted; if all its entries are priority entries the
+// value is not stored and SetForTenant returns false.
+func (ec *EmissionsCache) SetForTenant(tenant, key string, value interface{}, isPriority bool) bool {
+	if tenant == "" {
+		ec.Set(key, value, isPriority)
+		return true
+	}
+	limits := ec.tenants.limits[tenant]
+	if limits.MaxEntries > 0 {
+		ec.tenants.admit.Lock()
+		defer ec.tenants.admit.Unlock()
+		if !ec.tenants.owns(tenant, key) {
+			keys := ec.tenants.tenantKeys(tenant)
+			if len(keys) >= limits.MaxEntries && !ec.evictOne(keys) {
+				return false
+			}
+		}
+	}
+
+	ttl := cache.DefaultExpiration
+	switch {
+	case isPriority:
+		ttl = cache.NoExpiration
+	case limits.DefaultTTL > 0:
+		ttl = limits.DefaultTTL
+	}
+	ec.store.Set(key, value, ttl)
+	ec.sizes.set(key, value)
+	ec.tenants.track(tenant, key)
+	return true
+}
+
+// evictOne removes the standard entry among keys that expires first, reporting
+// whether one was found.
+func (ec *EmissionsCache) evictOne(keys []string) bool {
+	var victim string
+	var soonest time.Time
+	for _, key := range keys {
+		_, expiration, found := ec.store.GetWithExpiration(key)
+		if !found {
+			// Already expired; the janitor has not removed it yet.
+			victim = key
+			break
+		}
+		if expiration.IsZero() {
+			continue
+		}
+		if victim == "" || expiration.Before(soonest) {
+			victim, soonest = key, expiration
+		}
+	}
+	if victim == "" {
+		return false
+	}
+	// An entry that expired in the meantime has freed its slot all the same.
+	ec.deleteMu.Lock()
+	defer ec.deleteMu.Unlock()
+	ec.remove(victim)
+	return true
+}
+
+// TenantStats returns a snapshot of the entries and lookups of one tenant.
+func (ec *EmissionsCache) TenantStats(tenant string, topN int) Stats {
+	var stats Stats
+	for _, key := range ec.tenants.tenantKeys(tenant) {
+		_, expiration, found := ec.store.GetWithExpiration(key)
+		if found {
+			stats.addEntry(expiration.IsZero(), ec.sizes.get(key))
+		}
+	}
+	ec.tenants.recorder(tenant).snapshot(&stats, topN, nil)
+	return stats
+}
+
+// DeleteForTenant removes a single entry owned by tenant and reports whether
+// it was present. Entries of other tenants are left alone.
+func (ec *EmissionsCache) DeleteForTenant(tenant, key string) bool {
+	if !ec.tenants.owns(tenant, key) {
+		return false
+	}
+	removed := ec.Delete(key)
+	ec.tenants.untrack(key)
+	return removed
+}
+
+// FlushTenant removes every entry of one tenant, including priority entries,
+// and returns how many were removed.
+func (ec *EmissionsCache) FlushTenant(tenant string) int {
+	removed := 0
+	for _, key := range ec.tenants.tenantKeys(tenant) {
+		if ec.Delete(key) {
+			removed++
+		}
+		ec.tenants.untrack(key)
+	}
+	return removed
+}
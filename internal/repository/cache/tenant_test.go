@@ -0,0 +1,113 @@
+package cache_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/repository/cache"
+)
+
+func TestTenantLimits(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(time.Hour, time.Minute, 0, cache.WithTenantLimits(map[string]cache.TenantLimits{
+		"acme": {DefaultTTL: time.Minute, MaxEntries: 2},
+	}))
+
+	if !cacheRepo.SetForTenant("acme", "acme:pinned", "value", true) {
+		t.Fatal("Expected first entry to be stored")
+	}
+	cacheRepo.SetForTenant("acme", "acme:a", "value", false)
+	// At the limit, the standard entry is evicted to make room.
+	if !cacheRepo.SetForTenant("acme", "acme:b", "value", false) {
+		t.Fatal("Expected entry to be stored by evicting a standard entry")
+	}
+	if cacheRepo.Contains("acme:a") {
+		t.Error("Expected the standard entry to be evicted")
+	}
+	// Only priority entries left to evict: the new value is refused.
+	cacheRepo.SetForTenant("acme", "acme:c", "value", true)
+	if cacheRepo.SetForTenant("acme", "acme:d", "value", false) {
+		t.Error("Expected entry to be refused when only priority entries remain")
+	}
+
+	// Other tenants are unaffected.
+	for _, key := range []string{"globex:a", "globex:b", "globex:c"} {
+		if !cacheRepo.SetForTenant("globex", key, "value", false) {
+			t.Errorf("Expected unlimited tenant to store %s", key)
+		}
+	}
+}
+
+func TestTenantStatsAndFlush(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(time.Hour, time.Minute, 0)
+	cacheRepo.SetForTenant("acme", "acme:a", "value", false)
+	cacheRepo.SetForTenant("acme", "acme:b", "value", true)
+	cacheRepo.SetForTenant("globex", "globex:a", "value", false)
+	cacheRepo.Set("shared", "value", false)
+
+	cacheRepo.GetForTenant("acme", "acme:a")
+	cacheRepo.GetForTenant("acme", "acme:missing")
+	cacheRepo.GetForTenant("globex", "globex:a")
+
+	stats := cacheRepo.TenantStats("acme", 10)
+	if stats.Entries != 2 || stats.PriorityEntries != 1 {
+		t.Errorf("Expected 2 acme entries with 1 priority, got %+v", stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss for acme, got %d/%d", stats.Hits, stats.Misses)
+	}
+	cacheRepo.Get("shared")
+	all := cacheRepo.Stats(10)
+	if all.Entries != 4 || all.Hits != 3 {
+		t.Errorf("Expected cache-wide stats to cover every tenant, got %+v", all)
+	}
+	if len(all.TopKeys) != 1 || all.TopKeys[0].Key != "shared" {
+		t.Errorf("Expected cache-wide top keys to leave out tenant keys, got %+v", all.TopKeys)
+	}
+
+	if cacheRepo.DeleteForTenant("acme", "globex:a") {
+		t.Error("Expected DeleteForTenant to refuse another tenant's entry")
+	}
+	if !cacheRepo.Contains("globex:a") {
+		t.Error("Expected globex entry to survive a delete by acme")
+	}
+
+	if removed := cacheRepo.FlushTenant("acme"); removed != 2 {
+		t.Errorf("Expected FlushTenant to remove 2 entries, got %d", removed)
+	}
+	if !cacheRepo.Contains("globex:a") || !cacheRepo.Contains("shared") {
+		t.Error("Expected other tenants' and shared entries to survive")
+	}
+	if stats := cacheRepo.TenantStats("acme", 10); stats.Entries != 0 {
+		t.Errorf("Expected no acme entries after flush, got %d", stats.Entries)
+	}
+}
+
+func TestTenantLimitsConcurrentSets(t *testing.T) {
+	const limit = 5
+	cacheRepo := cache.NewInMemoryCache(time.Hour, time.Minute, 0, cache.WithTenantLimits(map[string]cache.TenantLimits{
+		"acme": {MaxEntries: limit},
+	}))
+
+	// Priority entries cannot be evicted, so exactly limit sets may succeed.
+	var stored int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if cacheRepo.SetForTenant("acme", fmt.Sprintf("acme:%d", i), "value", true) {
+				atomic.AddInt32(&stored, 1)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	if stored != limit {
+		t.Errorf("Expected %d entries to be stored, got %d", limit, stored)
+	}
+}
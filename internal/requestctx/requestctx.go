@@ -0,0 +1,104 @@
+package requestctx
+
+import (
+	"context"
+	"sync"
+)
+
+// contextKey is unexported to avoid collisions with other packages.
+type contextKey struct{}
+
+// Info carries per-request annotations collected while a request is handled,
+// such as the calling client and how many rows were served from the cache.
+// All methods are safe on a nil receiver so callers need not check presence.
+type Info struct {
+	mu         sync.Mutex
+	requestID  string
+	clientID   string
+	tenant     string
+	rows       int
+	cachedRows int
+}
+
+// New returns a context carrying a fresh Info for the given request ID, along
+// with the Info itself.
+func New(ctx context.Context, requestID string) (context.Context, *Info) {
+	info := &Info{requestID: requestID}
+	return context.WithValue(ctx, contextKey{}, info), info
+}
+
+// FromContext returns the Info attached to ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Info {
+	info, _ := ctx.Value(contextKey{}).(*Info)
+	return info
+}
+
+// RequestID returns the ID assigned to the request.
+func (i *Info) RequestID() string {
+	if i == nil {
+		return ""
+	}
+	return i.requestID
+}
+
+// SetClientID records the identity of the caller.
+func (i *Info) SetClientID(id string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.clientID = id
+}
+
+// ClientID returns the identity of the caller, if known.
+func (i *Info) ClientID() string {
+	if i == nil {
+		return ""
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.clientID
+}
+
+// SetTenant records the tenant the caller acts for.
+func (i *Info) SetTenant(tenant string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tenant = tenant
+}
+
+// Tenant returns the tenant the caller acts for, or "" for the shared
+// namespace.
+func (i *Info) Tenant() string {
+	if i == nil {
+		return ""
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.tenant
+}
+
+// SetRows records how many rows were requested and how many were cache hits.
+func (i *Info) SetRows(total, cached int) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rows = total
+	i.cachedRows = cached
+}
+
+// Rows returns the number of requested rows and cache hits.
+func (i *Info) Rows() (total, cached int) {
+	if i == nil {
+		return 0, 0
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rows, i.cachedRows
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"emissions-cache-service/internal/requestctx"
+)
+
+// Supported access log formats.
+const (
+	AccessLogFormatCommon   = "common"
+	AccessLogFormatCombined = "combined"
+	AccessLogFormatJSON     = "json"
+)
+
+// AccessLogConfig controls what the access log middleware records.
+type AccessLogConfig struct {
+	// Format is one of common, combined or json.
+	Format string
+	// SampleRate is the fraction of successful requests to log, between 0 and 1.
+	// Server errors are always logged.
+	SampleRate float64
+	// ExcludePaths lists request paths that are never logged, e.g. health checks.
+	ExcludePaths []string
+}
+
+// accessLogEntry is the JSON representation of one access log line.
+type accessLogEntry struct {
+	Time          string  `json:"time"`
+	RequestID     string  `json:"requestId,omitempty"`
+	RemoteAddr    string  `json:"remoteAddr"`
+	Client        string  `json:"client,omitempty"`
+	Method        string  `json:"method"`
+	Path          string  `json:"path"`
+	Protocol      string  `json:"protocol"`
+	Status        int     `json:"status"`
+	ResponseBytes int     `json:"responseBytes"`
+	DurationMs    float64 `json:"durationMs"`
+	Rows          int     `json:"rows"`
+	CacheHitRatio float64 `json:"cacheHitRatio"`
+	Referer       string  `json:"referer,omitempty"`
+	UserAgent     string  `json:"userAgent,omitempty"`
+}
+
+// responseRecorder captures the status code and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader records the status code before delegating.
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.status == 0 {
+		rr.status = code
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written before delegating.
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// Flush forwards to the underlying writer so streaming responses still work.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// accessLogger writes one line per request in the configured format.
+type accessLogger struct {
+	cfg     AccessLogConfig
+	exclude map[string]bool
+	out     *log.Logger
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// newAccessLogger creates an accessLogger writing to w.
+func newAccessLogger(cfg AccessLogConfig, w io.Writer) *accessLogger {
+	exclude := make(map[string]bool, len(cfg.ExcludePaths))
+	for _, p := range cfg.ExcludePaths {
+		exclude[p] = true
+	}
+	if cfg.Format == "" {
+		cfg.Format = AccessLogFormatCombined
+	}
+	return &accessLogger{
+		cfg:     cfg,
+		exclude: exclude,
+		out:     log.New(w, "", 0),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// middleware records every non-excluded, sampled request.
+func (al *accessLogger) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if al.exclude[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status < http.StatusInternalServerError && !al.sampled() {
+			return
+		}
+		al.write(r, rec, requestctx.FromContext(r.Context()), start, duration)
+	})
+}
+
+// sampled reports whether the current request falls within the sample rate.
+func (al *accessLogger) sampled() bool {
+	if al.cfg.SampleRate >= 1 {
+		return true
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.rand.Float64() < al.cfg.SampleRate
+}
+
+// write emits the log line for a completed request.
+func (al *accessLogger) write(r *http.Request, rec *responseRecorder, info *requestctx.Info, start time.Time, duration time.Duration) {
+	rows, cached := info.Rows()
+	hitRatio := 0.0
+	if rows > 0 {
+		hitRatio = float64(cached) / float64(rows)
+	}
+	entry := accessLogEntry{
+		Time:          start.UTC().Format(time.RFC3339Nano),
+		RequestID:     rec.Header().Get("X-Request-ID"),
+		RemoteAddr:    remoteHost(r.RemoteAddr),
+		Client:        info.ClientID(),
+		Method:        r.Method,
+		Path:          r.URL.RequestURI(),
+		Protocol:      r.Proto,
+		Status:        rec.status,
+		ResponseBytes: rec.bytes,
+		DurationMs:    float64(duration) / float64(time.Millisecond),
+		Rows:          rows,
+		CacheHitRatio: hitRatio,
+		Referer:       r.Referer(),
+		UserAgent:     r.UserAgent(),
+	}
+
+	switch al.cfg.Format {
+	case AccessLogFormatJSON:
+		b, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("failed to encode access log entry: %v", err)
+			return
+		}
+		al.out.Print(string(b))
+	default:
+		al.out.Print(al.formatText(entry, start))
+	}
+}
+
+// formatText renders an entry in Common or Combined Log Format, followed by
+// the service-specific fields as key=value pairs.
+func (al *accessLogger) formatText(e accessLogEntry, start time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s [%s] %q %d %d",
+		e.RemoteAddr,
+		dashIfEmpty(e.Client),
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method+" "+e.Path+" "+e.Protocol,
+		e.Status,
+		e.ResponseBytes,
+	)
+	if al.cfg.Format == AccessLogFormatCombined {
+		fmt.Fprintf(&b, " %q %q", dashIfEmpty(e.Referer), dashIfEmpty(e.UserAgent))
+	}
+	fmt.Fprintf(&b, " request_id=%s rows=%d cache_hit_ratio=%.2f duration_ms=%.3f",
+		dashIfEmpty(e.RequestID), e.Rows, e.CacheHitRatio, e.DurationMs)
+	return b.String()
+}
+
+// remoteHost strips the port from a remote address.
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// dashIfEmpty substitutes the conventional "-" placeholder for empty values.
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
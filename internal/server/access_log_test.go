@@ -0,0 +1,120 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/server"
+)
+
+type dummyMeasureService struct{}
+
+func (d *dummyMeasureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	return &models.MeasureResponse{
+		RequestID:      "unique-dummy-id",
+		TotalEmissions: 100.0,
+		Rows: []models.MeasureRowResponse{
+			{PropertyID: 1, TotalEmissions: 60.0, Cached: true},
+			{PropertyID: 2, TotalEmissions: 40.0, Cached: false},
+		},
+	}, nil
+}
+
+const measureBody = `{"rows":[{"country":"US","channel":"online","impressions":1000,"inventoryId":"a"},{"country":"US","channel":"online","impressions":1000,"inventoryId":"b"}]}`
+
+func TestAccessLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	srv := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithAccessLog(server.AccessLogConfig{Format: server.AccessLogFormatJSON, SampleRate: 1}, &buf))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody))
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON access log line, got %q: %v", buf.String(), err)
+	}
+	if entry["requestId"] != w.Header().Get("X-Request-ID") {
+		t.Errorf("Expected request ID %s, got %v", w.Header().Get("X-Request-ID"), entry["requestId"])
+	}
+	if entry["status"] != float64(http.StatusOK) {
+		t.Errorf("Expected status 200, got %v", entry["status"])
+	}
+	if entry["rows"] != float64(2) {
+		t.Errorf("Expected 2 rows, got %v", entry["rows"])
+	}
+	if entry["cacheHitRatio"] != 0.5 {
+		t.Errorf("Expected cache hit ratio 0.5, got %v", entry["cacheHitRatio"])
+	}
+	if entry["responseBytes"] != float64(w.Body.Len()) {
+		t.Errorf("Expected %d response bytes, got %v", w.Body.Len(), entry["responseBytes"])
+	}
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	var buf bytes.Buffer
+	srv := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithAccessLog(server.AccessLogConfig{Format: server.AccessLogFormatCombined, SampleRate: 1}, &buf))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody))
+	req.Header.Set("User-Agent", "test-agent")
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	for _, want := range []string{`"POST /v1/emissions/measure HTTP/1.1" 200`, `"-" "test-agent"`, "rows=2", "cache_hit_ratio=0.50"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected access log to contain %q, got %q", want, line)
+		}
+	}
+}
+
+func TestAccessLogExclusionAndSampling(t *testing.T) {
+	var buf bytes.Buffer
+	srv := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithAccessLog(server.AccessLogConfig{SampleRate: 1, ExcludePaths: []string{"/v1/health"}}, &buf))
+
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if buf.Len() != 0 {
+		t.Errorf("Expected excluded path not to be logged, got %q", buf.String())
+	}
+
+	buf.Reset()
+	srv = server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithAccessLog(server.AccessLogConfig{SampleRate: 0}, &buf))
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if buf.Len() != 0 {
+		t.Errorf("Expected unsampled request not to be logged, got %q", buf.String())
+	}
+}
+
+func TestAccessLogUnmatchedRequests(t *testing.T) {
+	var buf bytes.Buffer
+	srv := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithAccessLog(server.AccessLogConfig{Format: server.AccessLogFormatCommon, SampleRate: 1}, &buf),
+		server.WithCORS(server.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}, AllowedMethods: []string{"POST"}}))
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/v1/emissions/measure", nil)
+	preflight.Header.Set("Origin", "https://dashboard.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/wp-login.php", nil),
+		httptest.NewRequest(http.MethodGet, "/v1/emissions/measure", nil),
+		preflight,
+	} {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, req)
+		want := `"` + req.Method + " " + req.URL.Path + ` HTTP/1.1" ` + strconv.Itoa(w.Code)
+		if !strings.Contains(buf.String(), want) || !strings.Contains(buf.String(), "request_id="+w.Header().Get("X-Request-ID")) {
+			t.Errorf("Expected %s %s to be logged with its request ID, got %q", req.Method, req.URL.Path, buf.String())
+		}
+	}
+}
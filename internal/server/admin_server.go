@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"emissions-cache-service/internal/handler"
+
+	"github.com/gorilla/mux"
+)
+
+// NewAdminServer creates the internal diagnostics server. It exposes pprof
+// profiles and runtime details and must never be bound to a public interface.
+// config is served verbatim at /debug/config and must already be redacted.
+// With metrics writers, it also serves /metrics for Prometheus.
+func NewAdminServer(host string, port int, config interface{}, metrics ...handler.MetricsWriter) *HTTPServer {
+	r := mux.NewRouter()
+
+	// Initialize handlers.
+	diagnosticsHandler := handler.NewDiagnosticsHandler(config)
+
+	// Register routes.
+	r.HandleFunc("/debug/build", diagnosticsHandler.BuildInfo).Methods("GET")
+	r.HandleFunc("/debug/runtime", diagnosticsHandler.RuntimeStats).Methods("GET")
+	r.HandleFunc("/debug/config", diagnosticsHandler.Config).Methods("GET")
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// The index also serves named profiles such as heap and goroutine.
+	r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	if len(metrics) > 0 {
+		metricsHandler := handler.NewMetricsHandler(metrics...)
+		r.HandleFunc("/metrics", metricsHandler.Metrics).Methods("GET")
+	}
+
+	// Apply middleware.
+	r.Use(requestIDMiddleware)
+	r.Use(recoveryMiddleware)
+
+	addr := fmt.Sprintf("%s:%d", host, port)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: r,
+	}
+
+	return &HTTPServer{Server: srv, router: r}
+}
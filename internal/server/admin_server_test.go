@@ -0,0 +1,54 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/server"
+	"emissions-cache-service/internal/slo"
+)
+
+func TestAdminServerRoutes(t *testing.T) {
+	srv := server.NewAdminServer("127.0.0.1", 0, map[string]interface{}{"scope3": map[string]string{"token": "[REDACTED]"}})
+
+	for _, path := range []string{"/debug/build", "/debug/runtime", "/debug/config", "/debug/pprof/", "/debug/pprof/goroutine"} {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status OK for %s, got %v", path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	var stats map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode runtime stats: %v", err)
+	}
+	if stats["goroutines"].(float64) < 1 {
+		t.Errorf("Expected at least one goroutine, got %v", stats["goroutines"])
+	}
+}
+
+func TestMetricsOnlyOnAdminServer(t *testing.T) {
+	tracker := slo.NewTracker(time.Hour, map[slo.Path]slo.Objective{
+		slo.PathCached: {Threshold: 10 * time.Millisecond, Target: 0.99},
+	})
+	admin := server.NewAdminServer("127.0.0.1", 0, nil, tracker)
+	w := httptest.NewRecorder()
+	admin.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected metrics on the admin server, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	public := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0, server.WithSLOTracker(tracker))
+	w = httptest.NewRecorder()
+	public.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected no metrics on the public server, got %d", w.Code)
+	}
+}
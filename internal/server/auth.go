@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"emissions-cache-service/internal/auth"
+	svcerrors "emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/requestctx"
+)
+
+// authMiddleware resolves the caller with the first authenticator that
+// recognises the request's credentials and rejects unauthenticated requests,
+// except on exempt paths such as health checks or when optional is set.
+func authMiddleware(authenticators []auth.Authenticator, exemptPaths []string, optional bool) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, p := range exemptPaths {
+		exempt[p] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, a := range authenticators {
+				id, err := a.Authenticate(r)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					unauthorized(w, "invalid credentials")
+					return
+				}
+				info := requestctx.FromContext(r.Context())
+				info.SetClientID(id.ClientID)
+				info.SetTenant(id.Tenant)
+				next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), id)))
+				return
+			}
+			if optional {
+				next.ServeHTTP(w, r)
+				return
+			}
+			unauthorized(w, "authentication required")
+		})
+	}
+}
+
+// requireScope rejects authenticated callers lacking scope with 403. Requests
+// without an identity pass through, since they only reach a route when
+// authentication is disabled or the path is exempt.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id, ok := auth.FromContext(r.Context()); ok && !id.HasScope(scope) {
+				handler.WriteError(w, svcerrors.NewForbiddenError("missing required scope "+scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireIdentity rejects requests without an authenticated caller with 401.
+// It guards routes that must not be served anonymously even when
+// authentication is optional or the path is exempt.
+func requireIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.FromContext(r.Context()); !ok {
+			unauthorized(w, "authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unauthorized sends a 401 response.
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Add("WWW-Authenticate", `ApiKey header="`+auth.APIKeyHeader+`"`)
+	w.Header().Add("WWW-Authenticate", "Bearer")
+	handler.WriteError(w, svcerrors.NewUnauthorizedError(message))
+}
@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/repository/cache"
+	"emissions-cache-service/internal/server"
+)
+
+func newAuthenticatedServer(t *testing.T, logBuf *bytes.Buffer) *server.HTTPServer {
+	t.Helper()
+	store, err := auth.NewAPIKeyStore([]auth.APIKey{{ClientID: "dashboard", KeyHash: auth.HashAPIKey("secret")}}, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+	return server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithAccessLog(server.AccessLogConfig{Format: server.AccessLogFormatJSON, SampleRate: 1}, logBuf),
+		server.WithAuthentication([]string{"/v1/health"}, store),
+	)
+}
+
+func TestAuthenticationRequired(t *testing.T) {
+	var logBuf bytes.Buffer
+	srv := newAuthenticatedServer(t, &logBuf)
+
+	tests := []struct {
+		name string
+		path string
+		key  string
+		want int
+	}{
+		{name: "missing key", path: "/v1/emissions/measure", want: http.StatusUnauthorized},
+		{name: "unknown key", path: "/v1/emissions/measure", key: "nope", want: http.StatusUnauthorized},
+		{name: "valid key", path: "/v1/emissions/measure", key: "secret", want: http.StatusOK},
+		{name: "exempt path", path: "/v1/health", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodPost
+			if tt.path == "/v1/health" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, strings.NewReader(measureBody))
+			if tt.key != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestAuthenticationSetsClientIdentity(t *testing.T) {
+	var logBuf bytes.Buffer
+	srv := newAuthenticatedServer(t, &logBuf)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody))
+	req.Header.Set(auth.APIKeyHeader, "secret")
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logBuf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode access log: %v", err)
+	}
+	if entry["client"] != "dashboard" {
+		t.Errorf("Expected client identity 'dashboard' in access log, got %v", entry["client"])
+	}
+}
+
+type dummyCacheManager struct{}
+
+func (d *dummyCacheManager) Stats(topN int) cache.Stats { return cache.Stats{} }
+func (d *dummyCacheManager) TenantStats(tenant string, topN int) cache.Stats {
+	return cache.Stats{}
+}
+func (d *dummyCacheManager) Delete(key string) bool                  { return true }
+func (d *dummyCacheManager) DeleteForTenant(tenant, key string) bool { return true }
+func (d *dummyCacheManager) Flush() int                              { return 0 }
+func (d *dummyCacheManager) FlushTenant(tenant string) int           { return 0 }
+
+func TestScopeAuthorization(t *testing.T) {
+	store, err := auth.NewAPIKeyStore([]auth.APIKey{
+		{ClientID: "reader", KeyHash: auth.HashAPIKey("reader-key")},
+		{ClientID: "operator", KeyHash: auth.HashAPIKey("operator-key"), Scopes: []string{auth.ScopeCacheAdmin}},
+		{ClientID: "pinner", KeyHash: auth.HashAPIKey("pinner-key"), Scopes: []string{auth.ScopeMeasure, auth.ScopePriority}},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+	srv := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithCache(&dummyCacheManager{}),
+		server.WithAuthentication(nil, store),
+	)
+
+	priorityBody := `{"rows":[{"country":"US","channel":"online","impressions":1000,"inventoryId":"a","isPriority":true}]}`
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "default scopes allow measure", key: "reader-key", method: http.MethodPost, path: "/v1/emissions/measure", body: measureBody, want: http.StatusOK},
+		{name: "default scopes deny cache admin", key: "reader-key", method: http.MethodPost, path: "/v1/cache/flush", want: http.StatusForbidden},
+		{name: "default scopes deny priority", key: "reader-key", method: http.MethodPost, path: "/v1/emissions/measure", body: priorityBody, want: http.StatusForbidden},
+		{name: "cache admin allows flush", key: "operator-key", method: http.MethodPost, path: "/v1/cache/flush", want: http.StatusOK},
+		{name: "cache admin without measure", key: "operator-key", method: http.MethodPost, path: "/v1/emissions/measure", body: measureBody, want: http.StatusForbidden},
+		{name: "priority scope allows priority", key: "pinner-key", method: http.MethodPost, path: "/v1/emissions/measure", body: priorityBody, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutesRefuseAnonymousCallers(t *testing.T) {
+	// Without authentication the admin routes are not served at all.
+	open := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0, server.WithCache(&dummyCacheManager{}))
+	w := httptest.NewRecorder()
+	open.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/cache/flush", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected no flush route without authentication, got %d", w.Code)
+	}
+
+	// With optional authentication, anonymous callers may measure but not
+	// manage the cache.
+	optional := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithCache(&dummyCacheManager{}),
+		server.WithOptionalAuthentication(auth.NewClientCertAuthenticator(nil)),
+	)
+	w = httptest.NewRecorder()
+	optional.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/cache/flush", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an anonymous flush, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	optional.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected an anonymous measure request to succeed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig controls which browser origins may call the API.
+type CORSConfig struct {
+	// AllowedOrigins lists permitted origins such as "https://dash.example.com".
+	// "*" allows any origin and "https://*.example.com" any subdomain.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods permitted in preflight requests.
+	AllowedMethods []string
+	// AllowedHeaders lists request headers permitted in preflight requests;
+	// "*" allows any.
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers readable by the browser.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// cors applies a CORSConfig to requests.
+type cors struct {
+	cfg            CORSConfig
+	anyOrigin      bool
+	anyHeader      bool
+	allowedHeaders map[string]bool
+	allowedMethods map[string]bool
+}
+
+// newCORS creates a cors from cfg.
+func newCORS(cfg CORSConfig) *cors {
+	c := &cors{
+		cfg:            cfg,
+		allowedHeaders: make(map[string]bool),
+		allowedMethods: make(map[string]bool),
+	}
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			c.anyOrigin = true
+		}
+	}
+	for _, h := range cfg.AllowedHeaders {
+		if h == "*" {
+			c.anyHeader = true
+		}
+		c.allowedHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, m := range cfg.AllowedMethods {
+		c.allowedMethods[strings.ToUpper(m)] = true
+	}
+	return c
+}
+
+// middleware answers preflight requests itself and adds CORS headers to every
+// other response. It must wrap the router rather than be installed in it,
+// since routes only match their own methods and would reject OPTIONS.
+func (c *cors) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" || !c.originAllowed(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.setOrigin(w, origin)
+		if !preflight {
+			if len(c.cfg.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.cfg.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		requested := parseHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+		if !c.allowedMethods[method] || !c.headersAllowed(requested) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.cfg.AllowedMethods, ", "))
+		if len(requested) > 0 {
+			// Echoing the request covers "*", which browsers do not honour
+			// together with credentials.
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+		if c.cfg.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge/time.Second)))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// setOrigin sets the origin and credentials headers for an allowed origin.
+func (c *cors) setOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin && !c.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// originAllowed reports whether origin matches an allowed origin.
+func (c *cors) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	for _, allowed := range c.cfg.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// "https://*.example.com" matches any subdomain of example.com.
+		if i := strings.Index(allowed, "*."); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headersAllowed reports whether every requested header may be sent.
+func (c *cors) headersAllowed(requested []string) bool {
+	if c.anyHeader {
+		return true
+	}
+	for _, h := range requested {
+		if !c.allowedHeaders[http.CanonicalHeaderKey(h)] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList splits a comma-separated header list.
+func parseHeaderList(v string) []string {
+	var headers []string
+	for _, h := range strings.Split(v, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, h)
+		}
+	}
+	return headers
+}
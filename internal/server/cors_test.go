@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/server"
+)
+
+func newCORSServer(t *testing.T, credentials bool) *server.HTTPServer {
+	t.Helper()
+	store, err := auth.NewAPIKeyStore([]auth.APIKey{{ClientID: "dashboard", KeyHash: auth.HashAPIKey("secret")}}, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+	return server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithCORS(server.CORSConfig{
+			AllowedOrigins:   []string{"https://dashboard.example.com", "https://*.example.org"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowedHeaders:   []string{"Content-Type", "X-API-Key"},
+			ExposedHeaders:   []string{"X-Request-ID"},
+			AllowCredentials: credentials,
+			MaxAge:           10 * time.Minute,
+		}),
+		server.WithAuthentication([]string{"/v1/health"}, store),
+	)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	srv := newCORSServer(t, true)
+
+	tests := []struct {
+		name        string
+		origin      string
+		method      string
+		headers     string
+		wantAllowed bool
+	}{
+		{"allowed origin", "https://dashboard.example.com", "POST", "content-type, x-api-key", true},
+		{"wildcard subdomain", "https://eu.example.org", "POST", "", true},
+		{"unknown origin", "https://evil.example.net", "POST", "", false},
+		{"disallowed method", "https://dashboard.example.com", "PUT", "", false},
+		{"disallowed header", "https://dashboard.example.com", "POST", "X-Custom", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/v1/emissions/measure", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+
+			// Preflight succeeds without credentials even though the route requires them.
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d", w.Code)
+			}
+			allowed := w.Header().Get("Access-Control-Allow-Methods") != ""
+			if allowed != tt.wantAllowed {
+				t.Errorf("Expected allowed=%v, got headers %v", tt.wantAllowed, w.Header())
+			}
+			if allowed {
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.origin {
+					t.Errorf("Expected origin %s to be echoed, got %q", tt.origin, got)
+				}
+				if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+					t.Error("Expected credentials to be allowed")
+				}
+				if w.Header().Get("Access-Control-Max-Age") != "600" {
+					t.Errorf("Expected max age 600, got %q", w.Header().Get("Access-Control-Max-Age"))
+				}
+			}
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	srv := newCORSServer(t, false)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody))
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set(auth.APIKeyHeader, "secret")
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status OK, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://dashboard.example.com" {
+		t.Errorf("Expected allowed origin, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Expected exposed headers, got %q", got)
+	}
+
+	// Errors from disallowed origins carry no CORS headers.
+	req = httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody))
+	req.Header.Set("Origin", "https://evil.example.net")
+	w = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected 401 without CORS headers, got %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/requestctx"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDMetadataKey carries the request ID in response headers, like the
+// X-Request-ID HTTP header.
+const requestIDMetadataKey = "x-request-id"
+
+// wrappedStream overrides the context of a server stream.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the overridden context.
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+// withRequestID attaches a new request ID and requestctx.Info to ctx.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	reqID := uuid.New().String()
+	ctx, _ = requestctx.New(ctx, reqID)
+	return ctx, reqID
+}
+
+// requestIDUnaryInterceptor assigns each call a request ID, returned in the
+// x-request-id response header.
+func requestIDUnaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+	ctx, reqID := withRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, reqID))
+	return next(ctx, req)
+}
+
+// requestIDStreamInterceptor assigns each stream a request ID, returned in the
+// x-request-id response header.
+func requestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+	ctx, reqID := withRequestID(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestIDMetadataKey, reqID))
+	return next(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+// recoveryUnaryInterceptor turns panics into Internal errors.
+func recoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("panic in %s (request %s): %v", info.FullMethod, requestctx.FromContext(ctx).RequestID(), rec)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return next(ctx, req)
+}
+
+// recoveryStreamInterceptor turns panics into Internal errors.
+func recoveryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("panic in %s (request %s): %v", info.FullMethod, requestctx.FromContext(ss.Context()).RequestID(), rec)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return next(srv, ss)
+}
+
+// authUnaryInterceptor authenticates calls like authMiddleware and requires the
+// measure scope. It does nothing without authenticators.
+func authUnaryInterceptor(authenticators []auth.Authenticator, optional bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateRPC(ctx, authenticators, optional)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// authStreamInterceptor authenticates streams like authMiddleware and requires
+// the measure scope. It does nothing without authenticators.
+func authStreamInterceptor(authenticators []auth.Authenticator, optional bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+		ctx, err := authenticateRPC(ss.Context(), authenticators, optional)
+		if err != nil {
+			return err
+		}
+		return next(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticateRPC resolves the caller with the first authenticator that
+// recognises the call's credentials and attaches its identity to ctx. When
+// optional is set, calls without credentials proceed anonymously.
+func authenticateRPC(ctx context.Context, authenticators []auth.Authenticator, optional bool) (context.Context, error) {
+	if len(authenticators) == 0 {
+		return ctx, nil
+	}
+	r := rpcRequest(ctx)
+	for _, a := range authenticators {
+		id, err := a.Authenticate(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+		if !id.HasScope(auth.ScopeMeasure) {
+			return ctx, status.Error(codes.PermissionDenied, "missing required scope "+auth.ScopeMeasure)
+		}
+		info := requestctx.FromContext(ctx)
+		info.SetClientID(id.ClientID)
+		info.SetTenant(id.Tenant)
+		return auth.WithIdentity(ctx, id), nil
+	}
+	if optional {
+		return ctx, nil
+	}
+	return ctx, status.Error(codes.Unauthenticated, "authentication required")
+}
+
+// rpcRequest presents a call's metadata and TLS state as an HTTP request, so
+// the HTTP authenticators can be reused.
+func rpcRequest(ctx context.Context) *http.Request {
+	r := &http.Request{Header: http.Header{}}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			for _, v := range values {
+				r.Header.Add(key, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &tlsInfo.State
+		}
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	emissionsv1 "emissions-cache-service/api/emissions/v1"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// GRPCServer serves the EmissionsService gRPC API.
+type GRPCServer struct {
+	*grpc.Server
+	Addr string
+}
+
+// NewGRPCServer creates a gRPC server for the EmissionsService backed by
+// service. It accepts the same options as NewHTTPServer and honours those that
+// apply to it: authentication, rate limiting, request limits and TLS.
+func NewGRPCServer(service service.MeasureService, host string, port int, opts ...ServerOption) *GRPCServer {
+	var options serverOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	maxBodyBytes := options.maxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = handler.DefaultMaxBodyBytes
+	}
+	maxRows := options.maxRows
+	if maxRows <= 0 {
+		maxRows = handler.DefaultMaxRows
+	}
+
+	// Interceptors run in the same order as the HTTP middleware.
+	serverOpts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(int(maxBodyBytes)),
+		grpc.ChainUnaryInterceptor(
+			requestIDUnaryInterceptor,
+			recoveryUnaryInterceptor,
+			authUnaryInterceptor(options.authenticators, options.authOptional),
+		),
+		grpc.ChainStreamInterceptor(
+			requestIDStreamInterceptor,
+			recoveryStreamInterceptor,
+			authStreamInterceptor(options.authenticators, options.authOptional),
+		),
+	}
+	if options.tlsConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(options.tlsConfig)))
+	}
+
+	srv := grpc.NewServer(serverOpts...)
+	emissionsv1.RegisterEmissionsServiceServer(srv, &emissionsServer{
+		service: service,
+		limiter: options.rateLimiter,
+		maxRows: maxRows,
+	})
+	return &GRPCServer{Server: srv, Addr: fmt.Sprintf("%s:%d", host, port)}
+}
+
+// ListenAndServe listens on the server's address and serves gRPC requests
+// until the server is stopped.
+func (s *GRPCServer) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Shutdown stops accepting calls and waits for running ones to finish, or
+// stops them when ctx is done.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		return ctx.Err()
+	}
+}
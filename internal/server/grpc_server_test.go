@@ -0,0 +1,158 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	emissionsv1 "emissions-cache-service/api/emissions/v1"
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/server"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// rowsMeasureService answers each row with one emission, attributed to the
+// caller, and fails rows whose inventory ID is "fail".
+type rowsMeasureService struct{}
+
+func (s *rowsMeasureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	id, _ := auth.FromContext(ctx)
+	resp := &models.MeasureResponse{RequestID: id.ClientID}
+	for _, row := range req.Rows {
+		if row.InventoryID == "fail" {
+			return nil, errors.NewExternalError("upstream unavailable", nil)
+		}
+		resp.Rows = append(resp.Rows, models.MeasureRowResponse{PropertyName: row.InventoryID, TotalEmissions: float64(row.Impressions)})
+		resp.TotalEmissions += float64(row.Impressions)
+	}
+	return resp, nil
+}
+
+// newGRPCClient serves srv over an in-memory listener and returns a client.
+func newGRPCClient(t *testing.T, srv *server.GRPCServer) emissionsv1.EmissionsServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return emissionsv1.NewEmissionsServiceClient(conn)
+}
+
+func newAuthenticatedGRPCClient(t *testing.T) emissionsv1.EmissionsServiceClient {
+	t.Helper()
+	store, err := auth.NewAPIKeyStore([]auth.APIKey{
+		{ClientID: "bidder", KeyHash: auth.HashAPIKey("bidder-key"), Scopes: []string{auth.ScopeMeasure}},
+		{ClientID: "viewer", KeyHash: auth.HashAPIKey("viewer-key"), Scopes: []string{auth.ScopeCacheAdmin}},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyStore() error = %v", err)
+	}
+	srv := server.NewGRPCServer(&rowsMeasureService{}, "127.0.0.1", 0,
+		server.WithAuthentication(nil, store),
+		server.WithRequestLimits(0, 2),
+	)
+	return newGRPCClient(t, srv)
+}
+
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func row(inventoryID string) *emissionsv1.MeasureRow {
+	return &emissionsv1.MeasureRow{Country: "US", Channel: "web", Impressions: 10, InventoryId: inventoryID}
+}
+
+func TestGRPCMeasure(t *testing.T) {
+	client := newAuthenticatedGRPCClient(t)
+
+	var header metadata.MD
+	resp, err := client.Measure(withAPIKey("bidder-key"), &emissionsv1.MeasureRequest{Rows: []*emissionsv1.MeasureRow{row("a"), row("b")}}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Measure() error = %v", err)
+	}
+	if resp.RequestId != "bidder" || resp.TotalEmissions != 20 || len(resp.Rows) != 2 || resp.Rows[1].PropertyName != "b" {
+		t.Errorf("unexpected response: %v", resp)
+	}
+	if len(header.Get("x-request-id")) != 1 {
+		t.Errorf("expected an x-request-id header, got %v", header)
+	}
+}
+
+func TestGRPCMeasureErrors(t *testing.T) {
+	client := newAuthenticatedGRPCClient(t)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		rows []*emissionsv1.MeasureRow
+		code codes.Code
+	}{
+		{"no credentials", context.Background(), []*emissionsv1.MeasureRow{row("a")}, codes.Unauthenticated},
+		{"invalid key", withAPIKey("wrong"), []*emissionsv1.MeasureRow{row("a")}, codes.Unauthenticated},
+		{"missing scope", withAPIKey("viewer-key"), []*emissionsv1.MeasureRow{row("a")}, codes.PermissionDenied},
+		{"priority without scope", withAPIKey("bidder-key"), []*emissionsv1.MeasureRow{{Country: "US", Channel: "web", Impressions: 1, InventoryId: "a", IsPriority: true}}, codes.PermissionDenied},
+		{"too many rows", withAPIKey("bidder-key"), []*emissionsv1.MeasureRow{row("a"), row("b"), row("c")}, codes.ResourceExhausted},
+		{"upstream failure", withAPIKey("bidder-key"), []*emissionsv1.MeasureRow{row("fail")}, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Measure(tt.ctx, &emissionsv1.MeasureRequest{Rows: tt.rows})
+			if got := status.Code(err); got != tt.code {
+				t.Errorf("Measure() code = %v, want %v (%v)", got, tt.code, err)
+			}
+		})
+	}
+}
+
+func TestGRPCMeasureStream(t *testing.T) {
+	client := newAuthenticatedGRPCClient(t)
+	stream, err := client.MeasureStream(withAPIKey("bidder-key"))
+	if err != nil {
+		t.Fatalf("MeasureStream() error = %v", err)
+	}
+
+	batches := [][]*emissionsv1.MeasureRow{{row("a")}, {row("fail")}, {row("b"), row("c")}}
+	for _, rows := range batches {
+		if err := stream.Send(&emissionsv1.MeasureRequest{Rows: rows}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if rows[0].InventoryId == "fail" {
+			if resp.Error == nil || codes.Code(resp.Error.Code) != codes.Unavailable {
+				t.Errorf("expected an Unavailable batch error, got %v", resp)
+			}
+			continue
+		}
+		if resp.Error != nil || len(resp.Rows) != len(rows) || resp.RequestId != "bidder" {
+			t.Errorf("unexpected response: %v", resp)
+		}
+	}
+	stream.CloseSend()
+
+	// Streams are authenticated when they open.
+	stream, err = client.MeasureStream(context.Background())
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without credentials, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	emissionsv1 "emissions-cache-service/api/emissions/v1"
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/ratelimit"
+	"emissions-cache-service/internal/requestctx"
+	"emissions-cache-service/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// emissionsServer implements the EmissionsService on top of the measure service.
+type emissionsServer struct {
+	emissionsv1.UnimplementedEmissionsServiceServer
+
+	service service.MeasureService
+	limiter *ratelimit.Limiter
+	maxRows int
+}
+
+// Measure resolves one batch of rows.
+func (s *emissionsServer) Measure(ctx context.Context, req *emissionsv1.MeasureRequest) (*emissionsv1.MeasureResponse, error) {
+	resp, err := s.measure(ctx, req)
+	if err != nil {
+		if svcErr, ok := errors.As(err); ok && svcErr.RetryAfter > 0 {
+			grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(svcErr.RetryAfter.Seconds())))))
+		}
+		return nil, rpcError(err)
+	}
+	return resp, nil
+}
+
+// MeasureStream resolves each received batch and sends its response, in
+// order, until the client closes its side of the stream.
+func (s *emissionsServer) MeasureStream(stream emissionsv1.EmissionsService_MeasureStreamServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := s.measure(ctx, req)
+		if err != nil {
+			st := rpcError(err)
+			resp = &emissionsv1.MeasureResponse{Error: &emissionsv1.MeasureError{Code: int32(status.Code(st)), Message: status.Convert(st).Message()}}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// measure applies the checks the HTTP measure route makes, then calls the
+// measure service.
+func (s *emissionsServer) measure(ctx context.Context, req *emissionsv1.MeasureRequest) (*emissionsv1.MeasureResponse, error) {
+	if s.limiter != nil {
+		key := requestctx.FromContext(ctx).ClientID()
+		if p, ok := peer.FromContext(ctx); ok && key == "" {
+			key = remoteHost(p.Addr.String())
+		}
+		if ok, retryAfter := s.limiter.Allow(key); !ok {
+			return nil, errors.NewRateLimitedError("rate limit exceeded", retryAfter)
+		}
+	}
+	if len(req.Rows) > s.maxRows {
+		return nil, errors.NewPayloadTooLargeError(fmt.Sprintf("request contains %d rows; at most %d are allowed", len(req.Rows), s.maxRows))
+	}
+
+	rows := make([]models.MeasureRow, len(req.Rows))
+	id, authenticated := auth.FromContext(ctx)
+	for i, row := range req.Rows {
+		if row.IsPriority && authenticated && !id.HasScope(auth.ScopePriority) {
+			return nil, errors.NewForbiddenError(fmt.Sprintf("is_priority on row %d requires scope %s", i, auth.ScopePriority))
+		}
+		rows[i] = models.MeasureRow{
+			Country:     row.Country,
+			Channel:     row.Channel,
+			Impressions: int(row.Impressions),
+			InventoryID: row.InventoryId,
+			UTCDatetime: row.UtcDatetime,
+			IsPriority:  row.IsPriority,
+		}
+	}
+
+	resp, err := s.service.GetMeasure(ctx, models.MeasureRequest{Rows: rows})
+	requestctx.FromContext(ctx).SetRows(len(rows), countCachedRows(resp))
+	if err != nil {
+		return nil, err
+	}
+	out := &emissionsv1.MeasureResponse{
+		RequestId:      resp.RequestID,
+		TotalEmissions: resp.TotalEmissions,
+		Warnings:       resp.Warnings,
+		Rows:           make([]*emissionsv1.MeasureRowResult, len(resp.Rows)),
+	}
+	for i, row := range resp.Rows {
+		out.Rows[i] = &emissionsv1.MeasureRowResult{
+			PropertyId:        int64(row.PropertyID),
+			PropertyName:      row.PropertyName,
+			TotalEmissions:    row.TotalEmissions,
+			Cached:            row.Cached,
+			InventoryCoverage: row.InventoryCoverage,
+		}
+	}
+	return out, nil
+}
+
+// countCachedRows returns the number of rows served from the cache.
+func countCachedRows(resp *models.MeasureResponse) int {
+	if resp == nil {
+		return 0
+	}
+	cached := 0
+	for _, row := range resp.Rows {
+		if row.Cached {
+			cached++
+		}
+	}
+	return cached
+}
+
+// rpcError converts a service error to a gRPC status error with the same
+// client-facing message as the HTTP API.
+func rpcError(err error) error {
+	_, message := errors.ToHTTPError(err)
+	code := codes.Internal
+	if svcErr, ok := errors.As(err); ok {
+		switch svcErr.Type {
+		case errors.ErrorTypeValidation, errors.ErrorTypeUpstreamValidation:
+			code = codes.InvalidArgument
+		case errors.ErrorTypeNotFound:
+			code = codes.NotFound
+		case errors.ErrorTypeUnauthorized:
+			code = codes.Unauthenticated
+		case errors.ErrorTypeForbidden:
+			code = codes.PermissionDenied
+		case errors.ErrorTypeRateLimited, errors.ErrorTypePayloadTooLarge, errors.ErrorTypeUpstreamRateLimited:
+			code = codes.ResourceExhausted
+		case errors.ErrorTypeUpstreamUnavailable, errors.ErrorTypeUpstreamUnauthorized:
+			code = codes.Unavailable
+		case errors.ErrorTypeUpstreamTimeout:
+			code = codes.DeadlineExceeded
+		}
+	}
+	return status.Error(code, message)
+}
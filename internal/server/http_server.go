@@ -1,11 +1,22 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
+	"io"
 	"net/http"
+	"path"
 
+	"emissions-cache-service/api/openapi"
+	"emissions-cache-service/internal/audit"
+	"emissions-cache-service/internal/auth"
+	svcerrors "emissions-cache-service/internal/errors"
 	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/idempotency"
+	"emissions-cache-service/internal/ratelimit"
+	"emissions-cache-service/internal/requestctx"
 	"emissions-cache-service/internal/service"
+	"emissions-cache-service/internal/slo"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -21,43 +32,380 @@ func requestIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requestInfoMiddleware attaches a requestctx.Info that handlers and later
+// middleware annotate with per-request details.
+func requestInfoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := requestctx.New(r.Context(), r.Header.Get("X-Request-ID"))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // recoveryMiddleware recovers from panics and returns a 500 error.
 func recoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				handler.WriteError(w, svcerrors.NewInternalError("panic", fmt.Errorf("%v", rec)))
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
+// adminAuditMiddleware returns a wrapper that records every call to an admin
+// route, including its outcome, in the audit log.
+func adminAuditMiddleware(auditLog *audit.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if auditLog == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			auditLog.Record(r.Context(), audit.Event{
+				Action: audit.ActionAdminCall,
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Status: rec.status,
+			})
+		})
+	}
+}
+
 // HTTPServer wraps the http.Server.
 type HTTPServer struct {
 	*http.Server
+	router *mux.Router
+}
+
+// Router returns the routes served, without the middleware wrapping them.
+func (s *HTTPServer) Router() *mux.Router {
+	return s.router
+}
+
+// ServerOption defines a functional option for configuring the HTTP server.
+type ServerOption func(*serverOptions)
+
+// serverOptions collects the optional dependencies of the HTTP server.
+type serverOptions struct {
+	cache     handler.CacheManager
+	slo       *slo.Tracker
+	accessLog *accessLogger
+	auditLog  *audit.Logger
+
+	authenticators []auth.Authenticator
+	authExempt     []string
+	authOptional   bool
+
+	rateLimiter *ratelimit.Limiter
+	priority    handler.PriorityUsageReporter
+
+	maxBodyBytes int64
+	maxRows      int
+	stream       streamLimits
+	errorDetails bool
+
+	tlsConfig *tls.Config
+	cors      *cors
+
+	jobs            handler.JobManager
+	jobMaxBodyBytes int64
+	jobMaxRows      int
+	jobWebhooks     handler.JobWebhooks
+
+	idempotency *idempotency.Store
+
+	validateRequests bool
+}
+
+// WithCache exposes cache statistics and management endpoints under /v1/cache.
+func WithCache(c handler.CacheManager) ServerOption {
+	return func(o *serverOptions) {
+		o.cache = c
+	}
+}
+
+// WithAuditLogger records admin API calls and cache mutations made through them.
+func WithAuditLogger(auditLog *audit.Logger) ServerOption {
+	return func(o *serverOptions) {
+		o.auditLog = auditLog
+	}
+}
+
+// WithSLOTracker records measure latencies against the tracker and exposes its
+// report at /v1/admin/slo. Its metrics are served by the admin listener.
+func WithSLOTracker(tracker *slo.Tracker) ServerOption {
+	return func(o *serverOptions) {
+		o.slo = tracker
+	}
+}
+
+// WithAccessLog writes one access log line per request to w.
+func WithAccessLog(cfg AccessLogConfig, w io.Writer) ServerOption {
+	return func(o *serverOptions) {
+		o.accessLog = newAccessLogger(cfg, w)
+	}
+}
+
+// WithAuthentication requires every request, except those to exemptPaths, to
+// be authenticated by one of the authenticators, tried in order.
+func WithAuthentication(exemptPaths []string, authenticators ...auth.Authenticator) ServerOption {
+	return func(o *serverOptions) {
+		o.authenticators = authenticators
+		o.authExempt = exemptPaths
+		o.authOptional = false
+	}
+}
+
+// WithOptionalAuthentication identifies callers presenting credentials one of
+// the authenticators recognises, such as a client certificate when mutual TLS
+// is optional, and lets requests without credentials through anonymously.
+// Invalid credentials are still rejected.
+func WithOptionalAuthentication(authenticators ...auth.Authenticator) ServerOption {
+	return func(o *serverOptions) {
+		o.authenticators = authenticators
+		o.authExempt = nil
+		o.authOptional = true
+	}
+}
+
+// WithRateLimit applies a per-client request rate limit to the measure endpoint.
+func WithRateLimit(limiter *ratelimit.Limiter) ServerOption {
+	return func(o *serverOptions) {
+		o.rateLimiter = limiter
+	}
+}
+
+// WithPriorityUsage reports per-client priority slot usage at /v1/cache/priority.
+func WithPriorityUsage(reporter handler.PriorityUsageReporter) ServerOption {
+	return func(o *serverOptions) {
+		o.priority = reporter
+	}
+}
+
+// WithErrorDetails includes the underlying error in measure error responses.
+// It should only be enabled in development.
+func WithErrorDetails(enabled bool) ServerOption {
+	return func(o *serverOptions) {
+		o.errorDetails = enabled
+	}
+}
+
+// WithRequestLimits caps the body size and row count of measure requests.
+func WithRequestLimits(maxBodyBytes int64, maxRows int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxBodyBytes = maxBodyBytes
+		o.maxRows = maxRows
+	}
+}
+
+// streamLimits holds the limits of streamed measure requests.
+type streamLimits struct {
+	maxBodyBytes int64
+	maxRows      int
+	chunkSize    int
+}
+
+// WithStreamLimits caps the body size and row count of NDJSON measure requests
+// and sets how many rows are resolved at a time.
+func WithStreamLimits(maxBodyBytes int64, maxRows, chunkSize int) ServerOption {
+	return func(o *serverOptions) {
+		o.stream = streamLimits{maxBodyBytes: maxBodyBytes, maxRows: maxRows, chunkSize: chunkSize}
+	}
+}
+
+// WithRequestValidation rejects requests that do not match the OpenAPI
+// document served at /v1/openapi.json.
+func WithRequestValidation() ServerOption {
+	return func(o *serverOptions) {
+		o.validateRequests = true
+	}
+}
+
+// WithTLS serves HTTPS using cfg, typically built by NewTLSConfig.
+func WithTLS(cfg *tls.Config) ServerOption {
+	return func(o *serverOptions) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithCORS lets browsers on the configured origins call the API, answering
+// preflight requests on every route.
+func WithCORS(cfg CORSConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.cors = newCORS(cfg)
+	}
+}
+
+// WithJobs accepts asynchronous batch measurement jobs at /v1/emissions/jobs,
+// capping the body size and row count of each submission.
+func WithJobs(manager handler.JobManager, maxBodyBytes int64, maxRows int) ServerOption {
+	return func(o *serverOptions) {
+		o.jobs = manager
+		o.jobMaxBodyBytes = maxBodyBytes
+		o.jobMaxRows = maxRows
+	}
+}
+
+// WithJobWebhooks lets job submissions name a callback URL and reports the
+// deliveries made for a job at /v1/emissions/jobs/{id}/deliveries.
+func WithJobWebhooks(webhooks handler.JobWebhooks) ServerOption {
+	return func(o *serverOptions) {
+		o.jobWebhooks = webhooks
+	}
+}
+
+// WithIdempotency replays the stored response to measure and job submissions
+// repeated with the same Idempotency-Key header and body.
+func WithIdempotency(store *idempotency.Store) ServerOption {
+	return func(o *serverOptions) {
+		o.idempotency = store
+	}
 }
 
 // NewHTTPServer creates a new HTTP server with routes and middleware applied.
-func NewHTTPServer(service service.MeasureService, host string, port int) *HTTPServer {
+func NewHTTPServer(service service.MeasureService, host string, port int, opts ...ServerOption) *HTTPServer {
+	var options serverOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	r := mux.NewRouter()
 
+	// The embedded document is checked by the tests, so failing to load it
+	// is a build defect rather than a runtime condition.
+	doc, err := openapi.Load()
+	if err != nil {
+		panic(err)
+	}
+	openAPIHandler, err := handler.NewOpenAPIHandler(doc)
+	if err != nil {
+		panic(err)
+	}
+
 	// Initialize handlers.
-	measureHandler := handler.NewMeasureHandler(service)
+	measureOpts := []handler.MeasureHandlerOption{
+		handler.WithRequestLimits(options.maxBodyBytes, options.maxRows),
+		handler.WithStreamLimits(options.stream.maxBodyBytes, options.stream.maxRows, options.stream.chunkSize),
+		handler.WithErrorDetails(options.errorDetails),
+	}
+	if options.slo != nil {
+		measureOpts = append(measureOpts, handler.WithLatencyRecorder(options.slo))
+	}
+	measureHandler := handler.NewMeasureHandler(service, measureOpts...)
 
-	// Register routes.
-	r.HandleFunc("/v1/emissions/measure", measureHandler.Measure).Methods("POST")
+	// Register routes. Admin routes are audited, including refused attempts,
+	// and never served to anonymous callers.
+	audited := adminAuditMiddleware(options.auditLog)
+	admin := func(h http.HandlerFunc) http.Handler {
+		return audited(requireIdentity(requireScope(auth.ScopeCacheAdmin)(h)))
+	}
+	limited := rateLimitMiddleware(options.rateLimiter)
+	measureMaxBodyBytes := options.maxBodyBytes
+	if measureMaxBodyBytes <= 0 {
+		measureMaxBodyBytes = handler.DefaultMaxBodyBytes
+	}
+	idempotent := idempotencyMiddleware(options.idempotency, measureMaxBodyBytes)
+	r.Handle("/v1/emissions/measure", requireScope(auth.ScopeMeasure)(limited(idempotent(http.HandlerFunc(measureHandler.Measure))))).Methods("POST")
 	r.HandleFunc("/v1/health", measureHandler.HealthCheck).Methods("GET")
+	r.HandleFunc("/v1/openapi.json", openAPIHandler.Spec).Methods("GET")
+	if options.jobs != nil {
+		var jobsOpts []handler.JobsHandlerOption
+		if options.jobWebhooks != nil {
+			jobsOpts = append(jobsOpts, handler.WithJobWebhooks(options.jobWebhooks))
+		}
+		jobsHandler := handler.NewJobsHandler(options.jobs, options.jobMaxBodyBytes, options.jobMaxRows, jobsOpts...)
+		measure := requireScope(auth.ScopeMeasure)
+		idempotent := idempotencyMiddleware(options.idempotency, jobMaxBodyBytes(options))
+		r.Handle("/v1/emissions/jobs", measure(limited(idempotent(http.HandlerFunc(jobsHandler.Create))))).Methods("POST")
+		r.Handle("/v1/emissions/jobs/{id}", measure(http.HandlerFunc(jobsHandler.Get))).Methods("GET")
+		r.Handle("/v1/emissions/jobs/{id}/deliveries", measure(http.HandlerFunc(jobsHandler.Deliveries))).Methods("GET")
+	}
+	// The fixed /v1/emissions paths are never inventory IDs: a GET on them
+	// gets 405 (or 404 with jobs disabled) rather than a lookup. The reserved
+	// check precedes the path matcher, which would otherwise clear the method
+	// mismatch recorded by the fixed routes.
+	r.MatcherFunc(notReservedEmissionsPath).Path("/v1/emissions/{inventoryId}").Methods("GET").
+		Handler(requireScope(auth.ScopeMeasure)(limited(http.HandlerFunc(measureHandler.Lookup))))
+	// Without authenticators no caller could use the admin routes, so they
+	// are left out rather than served to everyone.
+	adminEnabled := len(options.authenticators) > 0
+	if options.cache != nil && adminEnabled {
+		cacheHandler := handler.NewCacheHandler(options.cache, options.auditLog)
+		r.Handle("/v1/cache/stats", admin(cacheHandler.Stats)).Methods("GET")
+		r.Handle("/v1/cache/entries/{key}", admin(cacheHandler.Delete)).Methods("DELETE")
+		r.Handle("/v1/cache/flush", admin(cacheHandler.Flush)).Methods("POST")
+	}
+	if options.priority != nil && adminEnabled {
+		priorityHandler := handler.NewPriorityHandler(options.priority)
+		r.Handle("/v1/cache/priority", admin(priorityHandler.Usage)).Methods("GET")
+	}
+	if options.slo != nil && adminEnabled {
+		sloHandler := handler.NewSLOHandler(options.slo)
+		r.Handle("/v1/admin/slo", admin(sloHandler.Report)).Methods("GET")
+	}
 
 	// Apply middleware.
-	r.Use(requestIDMiddleware)
 	r.Use(recoveryMiddleware)
+	if len(options.authenticators) > 0 {
+		r.Use(authMiddleware(options.authenticators, options.authExempt, options.authOptional))
+	}
+	if options.validateRequests {
+		validator := &requestValidator{doc: doc, maxBodyBytes: measureMaxBodyBytes, bodyLimits: map[string]int64{
+			"/v1/emissions/jobs": jobMaxBodyBytes(options),
+		}}
+		r.Use(validator.middleware)
+	}
+
+	// Wrap the router rather than using r.Use, which only applies to matched
+	// routes, so 404s, 405s and CORS preflights are logged too.
+	var h http.Handler = r
+	if options.cors != nil {
+		h = options.cors.middleware(h)
+	}
+	if options.accessLog != nil {
+		h = options.accessLog.middleware(h)
+	}
+	h = requestIDMiddleware(requestInfoMiddleware(h))
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: r,
+		Addr:      addr,
+		Handler:   h,
+		TLSConfig: options.tlsConfig,
+	}
+
+	return &HTTPServer{Server: srv, router: r}
+}
+
+// jobMaxBodyBytes returns the body size limit of job submissions.
+func jobMaxBodyBytes(options serverOptions) int64 {
+	if options.jobMaxBodyBytes <= 0 {
+		return handler.DefaultJobMaxBodyBytes
 	}
+	return options.jobMaxBodyBytes
+}
+
+// reservedEmissionsPaths are the fixed /v1/emissions/ segments.
+var reservedEmissionsPaths = map[string]bool{"measure": true, "jobs": true}
 
-	return &HTTPServer{srv}
+// notReservedEmissionsPath keeps the lookup route from matching the fixed
+// /v1/emissions/ paths. Unlike a pattern in the route variable, it leaves the
+// path template equal to the documented one.
+func notReservedEmissionsPath(r *http.Request, _ *mux.RouteMatch) bool {
+	return !reservedEmissionsPaths[path.Base(r.URL.Path)]
+}
+
+// ListenAndServe serves HTTPS when the server has a TLS configuration and
+// plain HTTP otherwise.
+func (s *HTTPServer) ListenAndServe() error {
+	if s.TLSConfig != nil {
+		// Certificates come from TLSConfig.GetCertificate.
+		return s.Server.ListenAndServeTLS("", "")
+	}
+	return s.Server.ListenAndServe()
 }
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	svcerrors "emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/idempotency"
+	"emissions-cache-service/internal/requestctx"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyMiddleware returns a wrapper that answers a request repeating an
+// earlier one with the same Idempotency-Key and body with the earlier
+// response, so a retried submission does not measure the rows again. Keys are
+// scoped to the caller and route. Only successful responses are stored;
+// failures release the key so the request can be retried. Bodies are read up
+// to maxBodyBytes to hash them, and requests without the header pass through.
+func idempotencyMiddleware(store *idempotency.Store, maxBodyBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				handler.WriteError(w, svcerrors.NewFieldValidationError(
+					fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+					svcerrors.FieldError{Field: "Idempotency-Key", Message: "too long"}))
+				return
+			}
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType == handler.ContentTypeNDJSON {
+				handler.WriteError(w, svcerrors.NewFieldValidationError(
+					"Idempotency-Key is not supported for streamed requests",
+					svcerrors.FieldError{Field: "Idempotency-Key", Message: "not supported for " + handler.ContentTypeNDJSON}))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					handler.WriteError(w, svcerrors.NewPayloadTooLargeError(fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes)))
+				} else {
+					handler.WriteError(w, svcerrors.NewValidationError("failed to read request body"))
+				}
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scoped := idempotencyScope(r) + "\x00" + r.URL.Path + "\x00" + key
+			stored, err := store.Begin(scoped, requestHash(r, body))
+			if err != nil {
+				handler.WriteError(w, err)
+				return
+			}
+			if stored != nil {
+				replay(w, stored)
+				return
+			}
+
+			rec := &capturingWriter{ResponseWriter: w}
+			completed := false
+			// Failed and panicking requests release the key for a retry.
+			defer func() {
+				if !completed {
+					store.Release(scoped)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if rec.status >= 200 && rec.status < 300 {
+				store.Complete(scoped, idempotency.Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()})
+				completed = true
+			}
+		})
+	}
+}
+
+// idempotencyScope identifies the caller that owns a key, falling back to the
+// remote address when unauthenticated like the rate limiter.
+func idempotencyScope(r *http.Request) string {
+	info := requestctx.FromContext(r.Context())
+	if id := info.ClientID(); id != "" {
+		return info.Tenant() + "\x00" + id
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+// requestHash identifies a request by its body and the headers that change
+// how the body is read or the response is written.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Header.Get("Content-Type")+"\n"+r.Header.Get("Accept")+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a stored response, marked with an Idempotent-Replayed header.
+// The request ID of the current request is kept.
+func replay(w http.ResponseWriter, stored *idempotency.Response) {
+	for name, values := range stored.Header {
+		if name == http.CanonicalHeaderKey("X-Request-ID") {
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// capturingWriter keeps a copy of the response it writes.
+type capturingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code and headers before delegating.
+func (cw *capturingWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+		cw.header = cw.ResponseWriter.Header().Clone()
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+// Write copies b before delegating.
+func (cw *capturingWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.body.Write(b)
+	return cw.ResponseWriter.Write(b)
+}
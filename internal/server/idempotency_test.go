@@ -0,0 +1,179 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/idempotency"
+	"emissions-cache-service/internal/jobs"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/server"
+)
+
+// countingMeasureService counts calls and can hold them until released.
+type countingMeasureService struct {
+	rowsMeasureService
+	mu      sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *countingMeasureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	if s.release != nil {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return s.rowsMeasureService.GetMeasure(ctx, req)
+}
+
+func (s *countingMeasureService) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func idempotentRequest(target, key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return req
+}
+
+func TestIdempotencyReplaysMeasureResponses(t *testing.T) {
+	svc := &countingMeasureService{}
+	srv := server.NewHTTPServer(svc, "localhost", 0,
+		server.WithIdempotency(idempotency.NewStore(time.Hour, 0)))
+	send := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, idempotentRequest("/v1/emissions/measure", key, body))
+		return w
+	}
+
+	first := send("key-1", measureBody)
+	second := send("key-1", measureBody)
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("Expected both requests to succeed, got %d and %d", first.Code, second.Code)
+	}
+	if svc.callCount() != 1 {
+		t.Errorf("Expected the rows to be measured once, got %d calls", svc.callCount())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("Expected only the repeated response to be marked as replayed")
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("Expected the stored response, got %s", second.Body)
+	}
+	if second.Header().Get("X-Request-ID") == first.Header().Get("X-Request-ID") {
+		t.Error("Expected the replay to keep its own request ID")
+	}
+
+	w := send("key-1", strings.Replace(measureBody, `"inventoryId":"a"`, `"inventoryId":"c"`, 1))
+	var problem errors.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusUnprocessableEntity || problem.Code != "IDEMPOTENCY_KEY_REUSED" {
+		t.Errorf("Expected 422 IDEMPOTENCY_KEY_REUSED for a different body, got %d %q", w.Code, problem.Code)
+	}
+
+	// Requests without a key are never replayed.
+	send("", measureBody)
+	send("", measureBody)
+	if svc.callCount() != 3 {
+		t.Errorf("Expected requests without a key to be measured, got %d calls", svc.callCount())
+	}
+}
+
+func TestIdempotencyDoesNotStoreFailures(t *testing.T) {
+	svc := &countingMeasureService{}
+	srv := server.NewHTTPServer(svc, "localhost", 0,
+		server.WithIdempotency(idempotency.NewStore(time.Hour, 0)))
+	failing := strings.Replace(measureBody, `"inventoryId":"a"`, `"inventoryId":"fail"`, 1)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, idempotentRequest("/v1/emissions/measure", "key-1", failing))
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("Expected a fresh 503, got %d", w.Code)
+		}
+	}
+	if svc.callCount() != 2 {
+		t.Errorf("Expected a failed request to be retried, got %d calls", svc.callCount())
+	}
+}
+
+func TestIdempotencyRejectsConcurrentRepeats(t *testing.T) {
+	svc := &countingMeasureService{started: make(chan struct{}), release: make(chan struct{})}
+	srv := server.NewHTTPServer(svc, "localhost", 0,
+		server.WithIdempotency(idempotency.NewStore(time.Hour, 0)))
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, idempotentRequest("/v1/emissions/measure", "key-1", measureBody))
+		done <- w.Code
+	}()
+	<-svc.started
+
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, idempotentRequest("/v1/emissions/measure", "key-1", measureBody))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while the first request runs, got %d", w.Code)
+	}
+	close(svc.release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected the first request to succeed, got %d", code)
+	}
+}
+
+func TestIdempotencyReplaysJobSubmissions(t *testing.T) {
+	manager := jobs.NewManager(&rowsMeasureService{})
+	srv := server.NewHTTPServer(&rowsMeasureService{}, "localhost", 0,
+		server.WithJobs(manager, 0, 0),
+		server.WithIdempotency(idempotency.NewStore(time.Hour, 0)),
+		server.WithAuthentication(nil, operatorAuthenticator{}))
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, idempotentRequest("/v1/emissions/jobs", "batch-1", measureBody))
+		var job jobs.Job
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil || w.Code != http.StatusAccepted {
+			t.Fatalf("Expected a queued job, got %d: %v", w.Code, err)
+		}
+		ids = append(ids, job.ID)
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("Expected the repeated submission to return job %s, got %s", ids[0], ids[1])
+	}
+
+	// The same key on another route is independent.
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, idempotentRequest("/v1/emissions/measure", "batch-1", measureBody))
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected keys to be scoped per route, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyRejectsStreams(t *testing.T) {
+	srv := server.NewHTTPServer(&rowsMeasureService{}, "localhost", 0,
+		server.WithIdempotency(idempotency.NewStore(time.Hour, 0)))
+	req := idempotentRequest("/v1/emissions/measure", "key-1", `{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}`+"\n")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a streamed request with a key, got %d", w.Code)
+	}
+}
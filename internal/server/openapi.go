@@ -0,0 +1,147 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	svcerrors "emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/handler"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+)
+
+// requestValidator rejects requests that do not match the OpenAPI document
+// before they reach a handler.
+type requestValidator struct {
+	doc *openapi3.T
+	// maxBodyBytes caps how much of a body is read for validation on routes
+	// without an entry in bodyLimits, which is keyed by path template and
+	// holds the limit of the route's handler.
+	maxBodyBytes int64
+	bodyLimits   map[string]int64
+}
+
+// bodyLimit returns the body size limit for the documented path.
+func (v *requestValidator) bodyLimit(path string) int64 {
+	if limit, ok := v.bodyLimits[path]; ok {
+		return limit
+	}
+	return v.maxBodyBytes
+}
+
+// middleware validates the path and query parameters, headers and JSON body
+// of each request against the operation documented for its route. Bodies of
+// other documented media types, such as NDJSON streams and CSV, are left to
+// the handlers so they are never buffered here.
+func (v *requestValidator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := v.route(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Bodies the handlers decode as JSON are validated as JSON whatever
+		// their Content-Type, matching the handlers' own behaviour.
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		excludeBody := route.Operation.RequestBody == nil ||
+			(mediaType != "application/json" && route.Operation.RequestBody.Value.Content.Get(mediaType) != nil)
+		limit := v.bodyLimit(route.Path)
+		vr := r
+		if !excludeBody {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			vr = r.WithContext(r.Context())
+			vr.Header = r.Header.Clone()
+			vr.Header.Set("Content-Type", "application/json")
+		}
+
+		err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    vr,
+			PathParams: mux.Vars(r),
+			Route:      route,
+			Options: &openapi3filter.Options{
+				ExcludeRequestBody:  excludeBody,
+				SkipSettingDefaults: true,
+				AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+			},
+		})
+		// The validator replaces the body it consumed with a copy.
+		r.Body = vr.Body
+		if err != nil {
+			handler.WriteError(w, toValidationError(err, limit))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// route returns the documented operation for the mux route r matched.
+func (v *requestValidator) route(r *http.Request) (*routers.Route, bool) {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return nil, false
+	}
+	path, err := current.GetPathTemplate()
+	if err != nil {
+		return nil, false
+	}
+	item := v.doc.Paths.Value(path)
+	if item == nil {
+		return nil, false
+	}
+	op := item.GetOperation(r.Method)
+	if op == nil {
+		return nil, false
+	}
+	return &routers.Route{Spec: v.doc, Path: path, PathItem: item, Method: r.Method, Operation: op}, true
+}
+
+// toValidationError converts a validation failure into the error reported to
+// the caller, naming the offending parameter or body field. maxBodyBytes is
+// the body size limit that applied.
+func toValidationError(err error, maxBodyBytes int64) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return svcerrors.NewPayloadTooLargeError(fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes))
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return svcerrors.NewValidationError(err.Error())
+	}
+	field, reason := "", reqErr.Reason
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(err, &schemaErr):
+		reason = schemaErr.Reason
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			field = "/" + strings.Join(pointer, "/")
+		}
+	case reqErr.Err == nil || reason == reqErr.Err.Error():
+	case reason == "":
+		reason = reqErr.Err.Error()
+	default:
+		reason += ": " + reqErr.Err.Error()
+	}
+
+	var message string
+	switch {
+	case reqErr.Parameter != nil:
+		field = reqErr.Parameter.Name
+		message = fmt.Sprintf("invalid %s parameter %s: %s", reqErr.Parameter.In, field, reason)
+	case field != "":
+		message = "invalid request body: " + field + ": " + reason
+	default:
+		message = "invalid request body: " + reason
+	}
+	if field == "" {
+		return svcerrors.NewValidationError(message)
+	}
+	return svcerrors.NewFieldValidationError(message, svcerrors.FieldError{Field: field, Message: reason})
+}
@@ -0,0 +1,251 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/api/openapi"
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/idempotency"
+	"emissions-cache-service/internal/jobs"
+	"emissions-cache-service/internal/policy"
+	"emissions-cache-service/internal/repository/cache"
+	"emissions-cache-service/internal/server"
+	"emissions-cache-service/internal/slo"
+	"emissions-cache-service/internal/webhook"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+)
+
+type dummyPriorityUsage struct{}
+
+func (d *dummyPriorityUsage) Usage() []policy.ClientUsage {
+	return []policy.ClientUsage{{ClientID: "dashboard", Used: 1, MaxEntries: 10}}
+}
+
+// operatorAuthenticator identifies every request as a caller with all scopes.
+type operatorAuthenticator struct{}
+
+func (operatorAuthenticator) Authenticate(*http.Request) (auth.Identity, error) {
+	return auth.Identity{ClientID: "operator", Scopes: []string{auth.ScopeMeasure, auth.ScopePriority, auth.ScopeCacheAdmin}}, nil
+}
+
+// newDocumentedServer returns a server with every optional route enabled and
+// the document describing it.
+func newDocumentedServer(t *testing.T) (*server.HTTPServer, *openapi3.T) {
+	t.Helper()
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	entries := cache.NewInMemoryCache(time.Minute, time.Minute, 0)
+	entries.Set("US-online-10-a", "cached", false)
+	entries.SetForTenant("acme", "acme:US-online-10-a", "cached", false)
+	tracker := slo.NewTracker(time.Hour, map[slo.Path]slo.Objective{
+		slo.PathCached:   {Threshold: 10 * time.Millisecond, Target: 0.99},
+		slo.PathUpstream: {Threshold: time.Second, Target: 0.95},
+	})
+	srv := server.NewHTTPServer(&rowsMeasureService{}, "localhost", 0,
+		server.WithRequestValidation(),
+		server.WithRequestLimits(1024, 10),
+		server.WithCache(entries),
+		server.WithSLOTracker(tracker),
+		server.WithPriorityUsage(&dummyPriorityUsage{}),
+		server.WithJobs(jobs.NewManager(&rowsMeasureService{}), 4096, 0),
+		server.WithJobWebhooks(webhook.NewNotifier("secret")),
+		server.WithIdempotency(idempotency.NewStore(time.Minute, 0)),
+		server.WithAuthentication(nil, operatorAuthenticator{}),
+	)
+	return srv, doc
+}
+
+// checkResponse validates a recorded response against the document.
+func checkResponse(t *testing.T, srv *server.HTTPServer, doc *openapi3.T, req *http.Request, w *httptest.ResponseRecorder) {
+	t.Helper()
+	var match mux.RouteMatch
+	if !srv.Router().Match(req, &match) || match.Route == nil {
+		t.Fatalf("%s %s: no route", req.Method, req.URL)
+	}
+	path, _ := match.Route.GetPathTemplate()
+	item := doc.Paths.Value(path)
+	if item == nil || item.GetOperation(req.Method) == nil {
+		t.Fatalf("%s %s is not documented", req.Method, path)
+	}
+	resp := w.Result()
+	err := openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: match.Vars,
+			Route:      &routers.Route{Spec: doc, Path: path, PathItem: item, Method: req.Method, Operation: item.GetOperation(req.Method)},
+		},
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   resp.Body,
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+			// Streams are not a single document the validator can decode.
+			ExcludeResponseBody: strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson"),
+		},
+	})
+	if err != nil {
+		t.Errorf("%s %s -> %d does not match the document: %v", req.Method, req.URL, resp.StatusCode, err)
+	}
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	srv, doc := newDocumentedServer(t)
+
+	registered := map[string]bool{}
+	srv.Router().Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			registered[method+" "+path] = true
+		}
+		return nil
+	})
+	documented := map[string]bool{}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for op := range registered {
+		if !documented[op] {
+			missing = append(missing, op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			stale = append(stale, op)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 || len(stale) > 0 {
+		t.Errorf("Routes not documented: %v; documented but not registered: %v", missing, stale)
+	}
+}
+
+func TestOpenAPIResponsesMatchDocument(t *testing.T) {
+	srv, doc := newDocumentedServer(t)
+	do := func(method, target, contentType, body string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, req)
+		checkResponse(t, srv, doc, req, w)
+		return w
+	}
+
+	do("GET", "/v1/health", "", "", nil)
+	if w := do("GET", "/v1/openapi.json", "", "", nil); !json.Valid(w.Body.Bytes()) {
+		t.Errorf("Expected the document as JSON, got %s", w.Body)
+	}
+
+	do("POST", "/v1/emissions/measure", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}]}`, nil)
+	do("POST", "/v1/emissions/measure", "text/csv", "country,channel,impressions,inventoryId\nUS,online,10,a\n", http.Header{"Accept": {"text/csv"}})
+	do("POST", "/v1/emissions/measure", "application/x-ndjson", `{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}`+"\n", nil)
+	if w := do("POST", "/v1/emissions/measure", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":10,"inventoryId":"fail"}]}`, nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for an upstream failure, got %d", w.Code)
+	}
+
+	keyed := http.Header{"Idempotency-Key": {"key-1"}}
+	do("POST", "/v1/emissions/measure", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}]}`, keyed)
+	if w := do("POST", "/v1/emissions/measure", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}]}`, keyed); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected a replayed response, got %d", w.Code)
+	}
+	if w := do("POST", "/v1/emissions/measure", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":20,"inventoryId":"a"}]}`, keyed); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a reused key, got %d", w.Code)
+	}
+
+	w := do("GET", "/v1/emissions/a?country=US&channel=online&impressions=10", "", "", nil)
+	if w := do("GET", "/v1/emissions/a?country=US&channel=online&impressions=10", "", "", http.Header{"If-None-Match": {w.Header().Get("ETag")}}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+
+	w = do("POST", "/v1/emissions/jobs", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}]}`, keyed)
+	var job jobs.Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil || w.Code != http.StatusAccepted {
+		t.Fatalf("Expected a queued job, got %d: %v", w.Code, err)
+	}
+	do("GET", "/v1/emissions/jobs/"+job.ID+"?limit=10", "", "", nil)
+	do("GET", "/v1/emissions/jobs/"+job.ID+"/deliveries", "", "", nil)
+	if w := do("GET", "/v1/emissions/jobs/missing", "", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", w.Code)
+	}
+
+	do("GET", "/v1/cache/stats?top=5", "", "", nil)
+	do("DELETE", "/v1/cache/entries/US-online-10-a", "", "", nil)
+	if w := do("DELETE", "/v1/cache/entries/acme:US-online-10-a", "", "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("Expected a tenant entry to be deleted, got %d", w.Code)
+	}
+	do("POST", "/v1/cache/flush?tenant=acme", "", "", nil)
+	do("GET", "/v1/cache/priority", "", "", nil)
+	do("GET", "/v1/admin/slo", "", "", nil)
+}
+
+func TestFixedEmissionsPathsAreNotLookups(t *testing.T) {
+	srv, _ := newDocumentedServer(t)
+	for _, path := range []string{"/v1/emissions/measure", "/v1/emissions/jobs"} {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?country=US&channel=online&impressions=10", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected 405 for GET %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestOpenAPIRequestValidation(t *testing.T) {
+	srv, doc := newDocumentedServer(t)
+	tests := []struct {
+		name, method, target, contentType, body string
+		status                                  int
+		message                                 string
+	}{
+		{"wrong type", "POST", "/v1/emissions/measure", "application/json", `{"rows":[{"country":"US","channel":"online","impressions":"many","inventoryId":"a"}]}`, 400, "invalid request body: /rows/0/impressions"},
+		{"unknown field", "POST", "/v1/emissions/measure", "application/json", `{"rows":[],"extra":1}`, 400, "invalid request body"},
+		{"missing field", "POST", "/v1/emissions/jobs", "application/json", `{"rows":[{"country":"US","impressions":1,"inventoryId":"a"}]}`, 400, "channel"},
+		{"no content type", "POST", "/v1/emissions/measure", "", `{"rows":[{"country":"US","channel":"online","impressions":0,"inventoryId":"a"}]}`, 400, "/rows/0/impressions"},
+		{"missing parameter", "GET", "/v1/emissions/a?channel=online&impressions=10", "", "", 400, "invalid query parameter country"},
+		{"bad parameter", "GET", "/v1/cache/stats?top=-1", "", "", 400, "invalid query parameter top"},
+		{"too large", "POST", "/v1/emissions/measure", "application/json", `{"rows":[` + strings.Repeat(" ", 8192) + `]}`, 413, "exceeds"},
+		// Each route is read up to its own handler's limit, 1024 bytes for
+		// measure and 4096 for jobs.
+		{"too large for measure", "POST", "/v1/emissions/measure", "application/json", `{"rows":[` + strings.Repeat(" ", 2048) + `],"extra":1}`, 413, "exceeds 1024 bytes"},
+		{"within the job limit", "POST", "/v1/emissions/jobs", "application/json", `{"rows":[` + strings.Repeat(" ", 2048) + `{"country":"US","channel":"online","impressions":10,"inventoryId":"a"}]}`, 202, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, req)
+			if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.message) {
+				t.Errorf("Expected %d containing %q, got %d: %s", tc.status, tc.message, w.Code, w.Body)
+			}
+			checkResponse(t, srv, doc, req, w)
+		})
+	}
+}
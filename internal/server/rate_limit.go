@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/internal/handler"
+	"emissions-cache-service/internal/ratelimit"
+	"emissions-cache-service/internal/requestctx"
+)
+
+// rateLimitMiddleware rejects requests beyond the caller's token bucket with
+// 429 and a Retry-After header. Callers are keyed by their authenticated
+// identity, falling back to the remote address when unauthenticated.
+func rateLimitMiddleware(limiter *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := requestctx.FromContext(r.Context()).ClientID()
+			if key == "" {
+				key = remoteHost(r.RemoteAddr)
+			}
+			if ok, retryAfter := limiter.Allow(key); !ok {
+				handler.WriteError(w, errors.NewRateLimitedError("rate limit exceeded", retryAfter))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/internal/ratelimit"
+	"emissions-cache-service/internal/server"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	srv := server.NewHTTPServer(&dummyMeasureService{}, "localhost", 0,
+		server.WithRateLimit(ratelimit.NewLimiter(0.01, 1)))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/emissions/measure", strings.NewReader(measureBody))
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(); w.Code != http.StatusOK {
+		t.Fatalf("Expected first request to succeed, got %d", w.Code)
+	}
+	w := send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on 429")
+	}
+
+	// Health checks are not rate limited.
+	health := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(health, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if health.Code != http.StatusOK {
+		t.Errorf("Expected health check to bypass the rate limit, got %d", health.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client certificate policies for mutual TLS.
+const (
+	// ClientAuthRequire rejects handshakes without a valid client certificate.
+	ClientAuthRequire = "require"
+	// ClientAuthOptional verifies a client certificate only if one is presented,
+	// so other authentication methods remain usable.
+	ClientAuthOptional = "optional"
+)
+
+// CertReloader serves a certificate and key pair, reloading it when either
+// file changes so certificates can be renewed without a restart.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the pair from certFile and keyFile.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload re-reads the certificate and key.
+func (c *CertReloader) Reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS key pair: %w", err)
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+// latestModTime returns the most recent modification time of the pair.
+func (c *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat TLS file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Watch polls the files every interval and reloads the pair when they change,
+// until ctx is cancelled. A pair that fails to load keeps the previous one.
+func (c *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, err := c.latestModTime()
+			if err != nil {
+				log.Printf("TLS certificate unavailable, keeping current certificate: %v", err)
+				continue
+			}
+			c.mu.RLock()
+			unchanged := modTime.Equal(c.modTime)
+			c.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+			if err := c.Reload(); err != nil {
+				log.Printf("Failed to reload TLS certificate, keeping current certificate: %v", err)
+				continue
+			}
+			log.Printf("Reloaded TLS certificate from %s", c.certFile)
+		}
+	}
+}
+
+// GetCertificate returns the current certificate; it is meant for
+// tls.Config.GetCertificate.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// NewTLSConfig builds server TLS settings that serve certificates from
+// reloader. When clientCAFile is set, client certificates are verified against
+// the bundle and, unless clientAuth is ClientAuthOptional, required.
+func NewTLSConfig(reloader *CertReloader, clientCAFile, clientAuth string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if clientCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA bundle %s", clientCAFile)
+	}
+	cfg.ClientCAs = pool
+	switch clientAuth {
+	case ClientAuthRequire, "":
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthOptional:
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", clientAuth)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,258 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/auth"
+	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/server"
+)
+
+// identityMeasureService echoes the caller's client ID as the request ID.
+type identityMeasureService struct{}
+
+func (s *identityMeasureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
+	id, _ := auth.FromContext(ctx)
+	return &models.MeasureResponse{RequestID: id.ClientID}, nil
+}
+
+// testCert is a certificate and key, both in memory and PEM-encoded.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert issues a certificate for commonName, signed by parent or
+// self-signed when parent is nil.
+func newTestCert(t *testing.T, commonName string, serial int64, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeFile writes data to name in dir and returns the path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCert(t, "server", 1, nil)
+	certFile := writeFile(t, dir, "tls.crt", first.certPEM)
+	keyFile := writeFile(t, dir, "tls.key", first.keyPEM)
+
+	reloader, err := server.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+
+	second := newTestCert(t, "server", 2, nil)
+	writeFile(t, dir, "tls.crt", second.certPEM)
+	writeFile(t, dir, "tls.key", second.keyPEM)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	cert, _ := reloader.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	if leaf.SerialNumber.Int64() != 2 {
+		t.Errorf("Expected reloaded certificate serial 2, got %d", leaf.SerialNumber)
+	}
+
+	// A broken pair is rejected and the current certificate kept.
+	writeFile(t, dir, "tls.key", first.keyPEM)
+	if err := reloader.Reload(); err == nil {
+		t.Error("Expected mismatched key pair to fail to load")
+	}
+	if current, _ := reloader.GetCertificate(nil); current != cert {
+		t.Error("Expected failed reload to keep the current certificate")
+	}
+}
+
+func TestMutualTLSIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", 1, nil)
+	serverCert := newTestCert(t, "server", 2, ca)
+	clientCert := newTestCert(t, "billing-job", 3, ca)
+
+	reloader, err := server.NewCertReloader(
+		writeFile(t, dir, "tls.crt", serverCert.certPEM),
+		writeFile(t, dir, "tls.key", serverCert.keyPEM))
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+	tlsConfig, err := server.NewTLSConfig(reloader, writeFile(t, dir, "ca.crt", ca.certPEM), server.ClientAuthRequire)
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+
+	srv := server.NewHTTPServer(&identityMeasureService{}, "127.0.0.1", 0,
+		server.WithTLS(tlsConfig),
+		server.WithAuthentication(nil, auth.NewClientCertAuthenticator(nil)),
+	)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+	url := "https://" + ln.Addr().String() + "/v1/emissions/measure"
+
+	if _, err := newClient().Post(url, "application/json", strings.NewReader(measureBody)); err == nil {
+		t.Error("Expected handshake without a client certificate to fail")
+	}
+
+	pair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair() error = %v", err)
+	}
+	resp, err := newClient(pair).Post(url, "application/json", strings.NewReader(measureBody))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK, got %d", resp.StatusCode)
+	}
+
+	var body models.MeasureResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.RequestID != "billing-job" {
+		t.Errorf("Expected client certificate subject as identity, got %q", body.RequestID)
+	}
+}
+
+func TestOptionalMutualTLSWithoutAuthentication(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", 1, nil)
+	serverCert := newTestCert(t, "server", 2, ca)
+	clientCert := newTestCert(t, "billing-job", 3, ca)
+
+	reloader, err := server.NewCertReloader(
+		writeFile(t, dir, "tls.crt", serverCert.certPEM),
+		writeFile(t, dir, "tls.key", serverCert.keyPEM))
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+	tlsConfig, err := server.NewTLSConfig(reloader, writeFile(t, dir, "ca.crt", ca.certPEM), server.ClientAuthOptional)
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+
+	srv := server.NewHTTPServer(&identityMeasureService{}, "127.0.0.1", 0,
+		server.WithTLS(tlsConfig),
+		server.WithOptionalAuthentication(auth.NewClientCertAuthenticator(nil)),
+	)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	pair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair() error = %v", err)
+	}
+	url := "https://" + ln.Addr().String() + "/v1/emissions/measure"
+
+	tests := []struct {
+		name  string
+		certs []tls.Certificate
+		want  string
+	}{
+		{name: "without certificate", want: ""},
+		{name: "with certificate", certs: []tls.Certificate{pair}, want: "billing-job"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: tt.certs}}}
+			resp, err := client.Post(url, "application/json", strings.NewReader(measureBody))
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status OK, got %d", resp.StatusCode)
+			}
+			var body models.MeasureResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.RequestID != tt.want {
+				t.Errorf("Expected identity %q, got %q", tt.want, body.RequestID)
+			}
+		})
+	}
+}
@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"emissions-cache-service/internal/audit"
 	"emissions-cache-service/internal/client/scope3"
 	"emissions-cache-service/internal/errors"
 	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/policy"
+	"emissions-cache-service/internal/ratelimit"
+	"emissions-cache-service/internal/requestctx"
 
 	"github.com/google/uuid"
 )
@@ -17,6 +24,21 @@ type CacheRepository interface {
 	Get(key string) (interface{}, bool)
 }
 
+// TenantCacheRepository is implemented by caches that apply per-tenant TTLs
+// and entry limits and keep per-tenant statistics. Keys are namespaced by the
+// service before they reach the cache.
+type TenantCacheRepository interface {
+	GetForTenant(tenant, key string) (interface{}, bool)
+	SetForTenant(tenant, key string, value interface{}, isPriority bool) bool
+}
+
+// ExpiringCacheRepository is implemented by caches that can report when an
+// entry expires, letting responses carry the remaining lifetime of each row.
+// A zero time means the entry never expires.
+type ExpiringCacheRepository interface {
+	Expiration(key string) (time.Time, bool)
+}
+
 // Scope3Client abstracts the Scope3 API client.
 type Scope3Client interface {
 	GetEmissions(ctx context.Context, req scope3.MeasureRequest) (*scope3.MeasureResponse, error)
@@ -31,47 +53,128 @@ type MeasureService interface {
 type measureService struct {
 	cache        CacheRepository
 	scope3Client Scope3Client
+	auditLog     *audit.Logger
+	// upstreamBudget limits, per client, how many rows may be sent to Scope3.
+	upstreamBudget *ratelimit.Limiter
+	// priorityPolicy limits who may create never-expiring entries.
+	priorityPolicy *policy.PriorityPolicy
+}
+
+// ServiceOption defines a functional option for configuring the measure service.
+type ServiceOption func(*measureService)
+
+// WithAuditLogger records every cache write made by the service.
+func WithAuditLogger(auditLog *audit.Logger) ServiceOption {
+	return func(m *measureService) {
+		m.auditLog = auditLog
+	}
+}
+
+// WithUpstreamBudget limits how many cache-missing rows each client may send
+// to Scope3, protecting the shared upstream quota.
+func WithUpstreamBudget(budget *ratelimit.Limiter) ServiceOption {
+	return func(m *measureService) {
+		m.upstreamBudget = budget
+	}
+}
+
+// WithPriorityPolicy restricts which clients may create priority entries.
+func WithPriorityPolicy(p *policy.PriorityPolicy) ServiceOption {
+	return func(m *measureService) {
+		m.priorityPolicy = p
+	}
 }
 
 // NewMeasureService creates a new instance of measureService.
-func NewMeasureService(cache CacheRepository, client Scope3Client) MeasureService {
-	return &measureService{
+func NewMeasureService(cache CacheRepository, client Scope3Client, opts ...ServiceOption) MeasureService {
+	m := &measureService{
 		cache:        cache,
 		scope3Client: client,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// anonymousClient is the budget key shared by unauthenticated callers.
+const anonymousClient = "anonymous"
+
+// keyEscaper escapes the tenant separator, and the characters that would stop
+// a key from being used as a URL path segment, in the parts of a cache key.
+var keyEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "/", "%2F")
+
+// generateCacheKey creates a composite key for caching based on key fields,
+// prefixed with the tenant and a ":" so tenants never share entries. Callers
+// without a tenant use the shared, unprefixed namespace. Every part is
+// escaped, so no row can produce another tenant's key.
+func generateCacheKey(tenant string, row models.MeasureRow) string {
+	key := fmt.Sprintf("%s-%s-%d-%s", keyEscaper.Replace(row.Country), keyEscaper.Replace(row.Channel),
+		row.Impressions, keyEscaper.Replace(row.InventoryID))
+	if tenant == "" {
+		return key
+	}
+	return keyEscaper.Replace(tenant) + ":" + key
+}
+
+// ValidateRows checks that rows is non-empty and that every row carries the
+// fields needed to build its cache key and query Scope3.
+func ValidateRows(rows []models.MeasureRow) error {
+	if len(rows) == 0 {
+		return errors.NewValidationError("no rows provided in request")
+	}
+	for i, row := range rows {
+		if err := ValidateRow(i, row); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// generateCacheKey creates a composite key for caching based on key fields.
-func generateCacheKey(row models.MeasureRow) string {
-	return fmt.Sprintf("%s-%s-%d-%s", row.Country, row.Channel, row.Impressions, row.InventoryID)
+// ValidateRow checks a single row, reporting problems against its index in
+// the request.
+func ValidateRow(i int, row models.MeasureRow) error {
+	if field, problem := RowProblem(row); problem != "" {
+		return errors.NewFieldValidationError(fmt.Sprintf("%s for row %d", problem, i),
+			errors.FieldError{Field: fmt.Sprintf("/rows/%d/%s", i, field), Message: problem})
+	}
+	return nil
+}
+
+// RowProblem describes the first problem with row and names the field at
+// fault, or returns an empty problem if the row is valid.
+func RowProblem(row models.MeasureRow) (field, problem string) {
+	switch {
+	case row.Country == "":
+		return "country", "country is required"
+	case row.Channel == "":
+		return "channel", "channel is required"
+	case row.Impressions <= 0:
+		return "impressions", "impressions must be positive"
+	case row.InventoryID == "":
+		return "inventoryId", "inventoryId is required"
+	}
+	return "", ""
 }
 
 // GetMeasure retrieves emissions data, either from cache or via the Scope3 API.
 func (m *measureService) GetMeasure(ctx context.Context, req models.MeasureRequest) (*models.MeasureResponse, error) {
-	if len(req.Rows) == 0 {
-		return nil, errors.NewValidationError("no rows provided in request")
+	if err := ValidateRows(req.Rows); err != nil {
+		return nil, err
 	}
 
-	// Validate each row.
-	for i, row := range req.Rows {
-		if row.Country == "" {
-			return nil, errors.NewValidationError(fmt.Sprintf("country is required for row %d", i))
-		}
-		if row.Channel == "" {
-			return nil, errors.NewValidationError(fmt.Sprintf("channel is required for row %d", i))
-		}
-		if row.Impressions <= 0 {
-			return nil, errors.NewValidationError(fmt.Sprintf("impressions must be positive for row %d", i))
-		}
-		if row.InventoryID == "" {
-			return nil, errors.NewValidationError(fmt.Sprintf("inventoryId is required for row %d", i))
-		}
+	tenant := requestctx.FromContext(ctx).Tenant()
+
+	// Apply the priority policy before any entry can be pinned.
+	rows, warnings, err := m.applyPriorityPolicy(ctx, req.Rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Map original rows by composite key.
 	originalRowsMap := make(map[string]models.MeasureRow)
-	for _, row := range req.Rows {
-		key := generateCacheKey(row)
+	for _, row := range rows {
+		key := generateCacheKey(tenant, row)
 		originalRowsMap[key] = row
 	}
 
@@ -79,15 +182,16 @@ func (m *measureService) GetMeasure(ctx context.Context, req models.MeasureReque
 	var uncachedRows []scope3.MeasureRow
 
 	// Check the cache for each row.
-	for _, row := range req.Rows {
-		key := generateCacheKey(row)
-		if cachedValue, found := m.cache.Get(key); found {
+	for _, row := range rows {
+		key := generateCacheKey(tenant, row)
+		if cachedValue, found := m.cacheGet(tenant, key); found {
 			if cachedRow, ok := cachedValue.(scope3.MeasureRowResponse); ok {
 				modelRows = append(modelRows, models.MeasureRowResponse{
 					PropertyID:     cachedRow.Internal.PropertyID,
 					PropertyName:   cachedRow.Internal.PropertyName,
 					TotalEmissions: cachedRow.TotalEmissions,
 					Cached:         true,
+					ExpiresAt:      m.cacheExpiration(key),
 				})
 				continue
 			}
@@ -111,19 +215,28 @@ func (m *measureService) GetMeasure(ctx context.Context, req models.MeasureReque
 			RequestID:      requestID,
 			TotalEmissions: sumEmissions(modelRows),
 			Rows:           modelRows,
+			Warnings:       warnings,
 		}, nil
 	}
 
+	if err := m.spendUpstreamBudget(ctx, len(uncachedRows)); err != nil {
+		return nil, err
+	}
+
 	// Call Scope3 API for rows that are not cached.
 	apiResponse, err := m.scope3Client.GetEmissions(ctx, scope3.MeasureRequest{Rows: uncachedRows})
 	if err != nil {
+		// Keep the cause when the client has already classified the failure.
+		if svcErr, ok := errors.As(err); ok && svcErr.Type.IsUpstream() {
+			return nil, err
+		}
 		return nil, errors.NewExternalError("failed to fetch emissions data from Scope3", err)
 	}
 
 	// Process API response and update the cache.
 	for i, apiRow := range apiResponse.Rows {
 		uncachedRow := uncachedRows[i]
-		key := generateCacheKey(models.MeasureRow{
+		key := generateCacheKey(tenant, models.MeasureRow{
 			Country:     uncachedRow.Country,
 			Channel:     uncachedRow.Channel,
 			Impressions: uncachedRow.Impressions,
@@ -136,12 +249,22 @@ func (m *measureService) GetMeasure(ctx context.Context, req models.MeasureReque
 				fmt.Errorf("could not find original row for key: %s", key),
 			)
 		}
-		m.cache.Set(key, apiRow, originalRow.IsPriority)
+		isPriority := originalRow.IsPriority
+		if isPriority && m.priorityPolicy != nil && !m.priorityPolicy.Reserve(clientID(ctx), key) {
+			isPriority = false
+			warnings = append(warnings, fmt.Sprintf("priority quota exhausted; inventoryId %s cached with default TTL", originalRow.InventoryID))
+		}
+		var expiresAt *time.Time
+		if m.cacheSet(tenant, key, apiRow, isPriority) {
+			m.auditCacheSet(ctx, key, apiRow, isPriority)
+			expiresAt = m.cacheExpiration(key)
+		}
 		// If the API indicates missing inventory coverage, mark accordingly.
 		if apiRow.InventoryCoverage == "missing" {
 			modelRows = append(modelRows, models.MeasureRowResponse{
 				InventoryCoverage: "missing",
 				Cached:            false,
+				ExpiresAt:         expiresAt,
 			})
 		} else {
 			modelRows = append(modelRows, models.MeasureRowResponse{
@@ -149,6 +272,7 @@ func (m *measureService) GetMeasure(ctx context.Context, req models.MeasureReque
 				PropertyName:   apiRow.Internal.PropertyName,
 				TotalEmissions: apiRow.TotalEmissions,
 				Cached:         false,
+				ExpiresAt:      expiresAt,
 			})
 		}
 	}
@@ -157,9 +281,115 @@ func (m *measureService) GetMeasure(ctx context.Context, req models.MeasureReque
 		RequestID:      requestID,
 		TotalEmissions: sumEmissions(modelRows),
 		Rows:           modelRows,
+		Warnings:       warnings,
 	}, nil
 }
 
+// applyPriorityPolicy returns the rows with isPriority cleared on rows the
+// caller may not pin, plus a warning for each, or an error in reject mode.
+func (m *measureService) applyPriorityPolicy(ctx context.Context, rows []models.MeasureRow) ([]models.MeasureRow, []string, error) {
+	if m.priorityPolicy == nil {
+		return rows, nil, nil
+	}
+	tenant := requestctx.FromContext(ctx).Tenant()
+	var candidates []policy.Candidate
+	var indexes []int
+	for i, row := range rows {
+		if row.IsPriority {
+			candidates = append(candidates, policy.Candidate{Key: generateCacheKey(tenant, row), InventoryID: row.InventoryID})
+			indexes = append(indexes, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return rows, nil, nil
+	}
+	allowed, reasons, err := m.priorityPolicy.Admit(clientID(ctx), candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+	adjusted := append([]models.MeasureRow(nil), rows...)
+	for i, ok := range allowed {
+		if !ok {
+			adjusted[indexes[i]].IsPriority = false
+		}
+	}
+	return adjusted, reasons, nil
+}
+
+// cacheGet looks key up on behalf of tenant.
+func (m *measureService) cacheGet(tenant, key string) (interface{}, bool) {
+	if tc, ok := m.cache.(TenantCacheRepository); ok {
+		return tc.GetForTenant(tenant, key)
+	}
+	return m.cache.Get(key)
+}
+
+// cacheSet stores value on behalf of tenant, reporting whether it was stored.
+func (m *measureService) cacheSet(tenant, key string, value interface{}, isPriority bool) bool {
+	if tc, ok := m.cache.(TenantCacheRepository); ok {
+		return tc.SetForTenant(tenant, key, value, isPriority)
+	}
+	m.cache.Set(key, value, isPriority)
+	return true
+}
+
+// cacheExpiration returns when the entry for key expires, or nil when it never
+// expires or the cache cannot tell.
+func (m *measureService) cacheExpiration(key string) *time.Time {
+	ec, ok := m.cache.(ExpiringCacheRepository)
+	if !ok {
+		return nil
+	}
+	expiration, found := ec.Expiration(key)
+	if !found || expiration.IsZero() {
+		return nil
+	}
+	return &expiration
+}
+
+// clientID returns the caller's identity, or a shared key when unauthenticated.
+func clientID(ctx context.Context) string {
+	if id := requestctx.FromContext(ctx).ClientID(); id != "" {
+		return id
+	}
+	return anonymousClient
+}
+
+// spendUpstreamBudget charges the calling client for rows about to be sent to
+// Scope3, failing with a rate-limited error when the budget is exhausted.
+func (m *measureService) spendUpstreamBudget(ctx context.Context, rows int) error {
+	if m.upstreamBudget == nil {
+		return nil
+	}
+	ok, retryAfter := m.upstreamBudget.AllowN(clientID(ctx), rows)
+	if ok {
+		return nil
+	}
+	if retryAfter == 0 {
+		return errors.NewRateLimitedError(
+			fmt.Sprintf("request needs %d upstream rows, more than the budget of %d", rows, m.upstreamBudget.Burst()), 0)
+	}
+	return errors.NewRateLimitedError("upstream row budget exhausted", retryAfter)
+}
+
+// auditCacheSet records a cache write populated from a Scope3 response.
+func (m *measureService) auditCacheSet(ctx context.Context, key string, value scope3.MeasureRowResponse, isPriority bool) {
+	if m.auditLog == nil {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		raw = nil
+	}
+	m.auditLog.Record(ctx, audit.Event{
+		Action:   audit.ActionCacheSet,
+		Source:   audit.SourceUpstream,
+		Key:      key,
+		Priority: isPriority,
+		Value:    raw,
+	})
+}
+
 // sumEmissions aggregates the total emissions from all rows.
 func sumEmissions(rows []models.MeasureRowResponse) float64 {
 	total := 0.0
@@ -2,19 +2,34 @@ package service_test
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"emissions-cache-service/internal/audit"
 	"emissions-cache-service/internal/client/scope3"
+	"emissions-cache-service/internal/errors"
 	"emissions-cache-service/internal/models"
+	"emissions-cache-service/internal/policy"
+	"emissions-cache-service/internal/ratelimit"
+	"emissions-cache-service/internal/repository/cache"
+	"emissions-cache-service/internal/requestctx"
 	"emissions-cache-service/internal/service"
 )
 
 type mockCache struct {
-	store map[string]interface{}
+	store    map[string]interface{}
+	priority map[string]bool
 }
 
 func (m *mockCache) Set(key string, value interface{}, isPriority bool) {
 	m.store[key] = value
+	if m.priority == nil {
+		m.priority = make(map[string]bool)
+	}
+	m.priority[key] = isPriority
 }
 
 func (m *mockCache) Get(key string) (interface{}, bool) {
@@ -22,6 +37,11 @@ func (m *mockCache) Get(key string) (interface{}, bool) {
 	return v, ok
 }
 
+func (m *mockCache) Contains(key string) bool {
+	_, ok := m.store[key]
+	return ok
+}
+
 type mockScope3Client struct {
 	response *scope3.MeasureResponse
 	err      error
@@ -234,3 +254,284 @@ func TestGetMeasureLargeRequest(t *testing.T) {
 		t.Errorf("Expected 100 rows, got %d", len(resp.Rows))
 	}
 }
+
+type memorySink struct {
+	events []audit.Event
+}
+
+func (m *memorySink) Write(e audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *memorySink) Close() error { return nil }
+
+func TestGetMeasureAuditsCacheWrites(t *testing.T) {
+	mockCacheRepo := &mockCache{store: make(map[string]interface{})}
+	mockScope3 := &mockScope3Client{
+		response: &scope3.MeasureResponse{
+			Rows: []scope3.MeasureRowResponse{{TotalEmissions: 40.0}},
+		},
+	}
+	sink := &memorySink{}
+	svc := service.NewMeasureService(mockCacheRepo, mockScope3, service.WithAuditLogger(audit.NewLogger(sink)))
+
+	req := models.MeasureRequest{
+		Rows: []models.MeasureRow{
+			{Country: "UK", Channel: "tv", Impressions: 500, InventoryID: "inv-002", IsPriority: true},
+		},
+	}
+	if _, err := svc.GetMeasure(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(sink.events) != 1 {
+		t.Fatalf("Expected 1 audit event, got %d", len(sink.events))
+	}
+	e := sink.events[0]
+	if e.Action != audit.ActionCacheSet || e.Source != audit.SourceUpstream || e.Key != "UK-tv-500-inv-002" || !e.Priority {
+		t.Errorf("Unexpected audit event: %+v", e)
+	}
+}
+
+func TestGetMeasureUpstreamBudget(t *testing.T) {
+	cacheStore := map[string]interface{}{
+		"US-online-1000-cached": scope3.MeasureRowResponse{TotalEmissions: 1.0},
+	}
+	mockScope3 := &mockScope3Client{
+		response: &scope3.MeasureResponse{Rows: []scope3.MeasureRowResponse{{TotalEmissions: 1.0}, {TotalEmissions: 1.0}}},
+	}
+	svc := service.NewMeasureService(&mockCache{store: cacheStore}, mockScope3,
+		service.WithUpstreamBudget(ratelimit.NewLimiter(0.001, 2)))
+
+	ctx, info := requestctx.New(context.Background(), "req-1")
+	info.SetClientID("client-a")
+	row := func(id string) models.MeasureRow {
+		return models.MeasureRow{Country: "US", Channel: "online", Impressions: 1000, InventoryID: id}
+	}
+
+	// Cached rows are free, so only the two misses are charged.
+	if _, err := svc.GetMeasure(ctx, models.MeasureRequest{Rows: []models.MeasureRow{row("cached"), row("a"), row("b")}}); err != nil {
+		t.Fatalf("Expected request within budget to succeed, got %v", err)
+	}
+
+	_, err := svc.GetMeasure(ctx, models.MeasureRequest{Rows: []models.MeasureRow{row("c")}})
+	svcErr, ok := err.(*errors.ServiceError)
+	if !ok || svcErr.Type != errors.ErrorTypeRateLimited {
+		t.Fatalf("Expected rate limited error, got %v", err)
+	}
+	if svcErr.RetryAfter <= 0 {
+		t.Errorf("Expected a retry-after hint, got %v", svcErr.RetryAfter)
+	}
+
+	// The budget is per client.
+	otherCtx, otherInfo := requestctx.New(context.Background(), "req-2")
+	otherInfo.SetClientID("client-b")
+	mockScope3.response = &scope3.MeasureResponse{Rows: []scope3.MeasureRowResponse{{TotalEmissions: 1.0}}}
+	if _, err := svc.GetMeasure(otherCtx, models.MeasureRequest{Rows: []models.MeasureRow{row("c")}}); err != nil {
+		t.Errorf("Expected another client's budget to be untouched, got %v", err)
+	}
+}
+
+func TestGetMeasurePriorityPolicy(t *testing.T) {
+	mockCacheRepo := &mockCache{store: make(map[string]interface{})}
+	mockScope3 := &mockScope3Client{
+		response: &scope3.MeasureResponse{
+			Rows: []scope3.MeasureRowResponse{{TotalEmissions: 1.0}, {TotalEmissions: 2.0}},
+		},
+	}
+	req := models.MeasureRequest{
+		Rows: []models.MeasureRow{
+			{Country: "US", Channel: "online", Impressions: 1000, InventoryID: "inv-001", IsPriority: true},
+			{Country: "UK", Channel: "tv", Impressions: 500, InventoryID: "inv-002", IsPriority: true},
+		},
+	}
+	rule := policy.Rule{AllowedInventoryIDs: []string{"inv-001"}}
+
+	rejecting := service.NewMeasureService(mockCacheRepo, mockScope3,
+		service.WithPriorityPolicy(policy.NewPriorityPolicy(policy.ModeReject, rule, nil, mockCacheRepo)))
+	_, err := rejecting.GetMeasure(context.Background(), req)
+	if svcErr, ok := err.(*errors.ServiceError); !ok || svcErr.Type != errors.ErrorTypeForbidden {
+		t.Fatalf("Expected forbidden error in reject mode, got %v", err)
+	}
+
+	downgrading := service.NewMeasureService(mockCacheRepo, mockScope3,
+		service.WithPriorityPolicy(policy.NewPriorityPolicy(policy.ModeDowngrade, rule, nil, mockCacheRepo)))
+	resp, err := downgrading.GetMeasure(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error in downgrade mode, got %v", err)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %v", resp.Warnings)
+	}
+	if !mockCacheRepo.priority["US-online-1000-inv-001"] || mockCacheRepo.priority["UK-tv-500-inv-002"] {
+		t.Errorf("Expected only the allowlisted row to be pinned, got %v", mockCacheRepo.priority)
+	}
+}
+
+func TestGetMeasureTenantNamespaces(t *testing.T) {
+	cacheStore := map[string]interface{}{
+		"US-online-1000-inv-001": scope3.MeasureRowResponse{TotalEmissions: 1.0},
+	}
+	mockCacheRepo := &mockCache{store: cacheStore}
+	mockScope3 := &mockScope3Client{
+		response: &scope3.MeasureResponse{Rows: []scope3.MeasureRowResponse{{TotalEmissions: 2.0}}},
+	}
+	svc := service.NewMeasureService(mockCacheRepo, mockScope3)
+	req := models.MeasureRequest{
+		Rows: []models.MeasureRow{{Country: "US", Channel: "online", Impressions: 1000, InventoryID: "inv-001"}},
+	}
+
+	// A tenant does not see the shared entry and caches under its own prefix.
+	ctx, info := requestctx.New(context.Background(), "req-1")
+	info.SetTenant("acme")
+	resp, err := svc.GetMeasure(ctx, req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.Rows[0].Cached || resp.TotalEmissions != 2.0 {
+		t.Errorf("Expected an upstream result for the tenant, got %+v", resp)
+	}
+	if _, ok := cacheStore["acme:US-online-1000-inv-001"]; !ok {
+		t.Errorf("Expected entry under the tenant namespace, got keys %v", cacheStore)
+	}
+
+	// Callers without a tenant keep using the shared namespace.
+	resp, err = svc.GetMeasure(context.Background(), req)
+	if err != nil || !resp.Rows[0].Cached || resp.TotalEmissions != 1.0 {
+		t.Errorf("Expected the shared cached entry, got %+v, %v", resp, err)
+	}
+}
+
+func TestGetMeasureTenantKeysCannotBeForged(t *testing.T) {
+	cacheStore := map[string]interface{}{
+		"acme:US-online-1000-inv-001": scope3.MeasureRowResponse{TotalEmissions: 1.0},
+	}
+	mockScope3 := &mockScope3Client{
+		response: &scope3.MeasureResponse{Rows: []scope3.MeasureRowResponse{{TotalEmissions: 2.0}}},
+	}
+	svc := service.NewMeasureService(&mockCache{store: cacheStore}, mockScope3)
+
+	// Neither a caller without a tenant nor another tenant can name acme's
+	// entry through the row fields.
+	tests := []struct {
+		name    string
+		tenant  string
+		country string
+	}{
+		{name: "no tenant", country: "acme:US"},
+		{name: "other tenant", tenant: "globex", country: "acme:US"},
+		{name: "slash separator", country: "acme/US"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, info := requestctx.New(context.Background(), "req-1")
+			info.SetTenant(tt.tenant)
+			resp, err := svc.GetMeasure(ctx, models.MeasureRequest{Rows: []models.MeasureRow{
+				{Country: tt.country, Channel: "online", Impressions: 1000, InventoryID: "inv-001"},
+			}})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if resp.Rows[0].Cached || resp.TotalEmissions != 2.0 {
+				t.Errorf("Expected an upstream result, got acme's entry: %+v", resp)
+			}
+		})
+	}
+	if len(cacheStore) != 4 {
+		t.Errorf("Expected one new entry per caller, got keys %v", cacheStore)
+	}
+}
+
+func TestGetMeasureReportsExpiry(t *testing.T) {
+	cacheRepo := cache.NewInMemoryCache(time.Hour, time.Minute, 0)
+	mockScope3 := &mockScope3Client{
+		response: &scope3.MeasureResponse{Rows: []scope3.MeasureRowResponse{{TotalEmissions: 2.0}}},
+	}
+	svc := service.NewMeasureService(cacheRepo, mockScope3)
+	row := models.MeasureRow{Country: "US", Channel: "online", Impressions: 1000, InventoryID: "inv-001"}
+
+	before := time.Now()
+	resp, err := svc.GetMeasure(context.Background(), models.MeasureRequest{Rows: []models.MeasureRow{row}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expiresAt := resp.Rows[0].ExpiresAt
+	if expiresAt == nil || expiresAt.Before(before.Add(time.Hour)) || expiresAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("Expected the new entry to expire in an hour, got %v", expiresAt)
+	}
+
+	// The cached row reports the same expiry.
+	resp, err = svc.GetMeasure(context.Background(), models.MeasureRequest{Rows: []models.MeasureRow{row}})
+	if err != nil || !resp.Rows[0].Cached || resp.Rows[0].ExpiresAt == nil || !resp.Rows[0].ExpiresAt.Equal(*expiresAt) {
+		t.Errorf("Expected the cached row to keep its expiry, got %+v, %v", resp, err)
+	}
+
+	// Priority entries never expire.
+	row.InventoryID = "inv-002"
+	row.IsPriority = true
+	resp, err = svc.GetMeasure(context.Background(), models.MeasureRequest{Rows: []models.MeasureRow{row}})
+	if err != nil || resp.Rows[0].ExpiresAt != nil {
+		t.Errorf("Expected no expiry for a priority entry, got %+v, %v", resp, err)
+	}
+}
+
+func TestGetMeasureKeepsUpstreamCause(t *testing.T) {
+	req := models.MeasureRequest{Rows: []models.MeasureRow{{Country: "US", Channel: "online", Impressions: 1000, InventoryID: "inv-001"}}}
+
+	rejected := errors.NewUpstreamValidationError("Scope3 API rejected the request (status: 400)", nil)
+	svc := service.NewMeasureService(&mockCache{store: map[string]interface{}{}}, &mockScope3Client{err: rejected})
+	if _, err := svc.GetMeasure(context.Background(), req); err != rejected {
+		t.Errorf("Expected the classified upstream error to be returned as is, got %v", err)
+	}
+
+	cause := fmt.Errorf("connection reset")
+	svc = service.NewMeasureService(&mockCache{store: map[string]interface{}{}}, &mockScope3Client{err: cause})
+	_, err := svc.GetMeasure(context.Background(), req)
+	svcErr, ok := errors.As(err)
+	if !ok || svcErr.Type != errors.ErrorTypeUpstreamUnavailable || !svcErr.IsRetryable() {
+		t.Fatalf("Expected a retryable unavailable error, got %v", err)
+	}
+	if !stderrors.Is(err, cause) {
+		t.Errorf("Expected the error to unwrap to its cause, got %v", err)
+	}
+}
+
+// hookScope3Client runs before while answering, e.g. to simulate a concurrent
+// request.
+type hookScope3Client struct {
+	mockScope3Client
+	before func()
+}
+
+func (h *hookScope3Client) GetEmissions(ctx context.Context, req scope3.MeasureRequest) (*scope3.MeasureResponse, error) {
+	h.before()
+	return h.mockScope3Client.GetEmissions(ctx, req)
+}
+
+func TestGetMeasurePriorityQuotaWarningHidesCacheKey(t *testing.T) {
+	mockCacheRepo := &mockCache{store: make(map[string]interface{})}
+	priority := policy.NewPriorityPolicy(policy.ModeDowngrade, policy.Rule{MaxEntries: 1}, nil, mockCacheRepo)
+	// Another request pins the client's only slot while Scope3 is called.
+	client := &hookScope3Client{
+		mockScope3Client: mockScope3Client{response: &scope3.MeasureResponse{Rows: []scope3.MeasureRowResponse{{TotalEmissions: 1.0}}}},
+		before: func() {
+			mockCacheRepo.Set("other-key", 1.0, true)
+			priority.Reserve("client-a", "other-key")
+		},
+	}
+	svc := service.NewMeasureService(mockCacheRepo, client, service.WithPriorityPolicy(priority))
+
+	ctx, info := requestctx.New(context.Background(), "req-1")
+	info.SetClientID("client-a")
+	info.SetTenant("acme")
+	resp, err := svc.GetMeasure(ctx, models.MeasureRequest{Rows: []models.MeasureRow{
+		{Country: "US", Channel: "online", Impressions: 1000, InventoryID: "inv-001", IsPriority: true},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "inventoryId inv-001") || strings.Contains(resp.Warnings[0], "acme") {
+		t.Errorf("Expected a warning naming the inventory ID only, got %v", resp.Warnings)
+	}
+}
@@ -0,0 +1,271 @@
+package slo
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Path identifies how a request was served.
+type Path string
+
+const (
+	// PathCached covers requests answered entirely from the cache.
+	PathCached Path = "cached"
+	// PathUpstream covers requests that needed at least one Scope3 call.
+	PathUpstream Path = "upstream"
+)
+
+// paths lists every tracked path in reporting order.
+var paths = []Path{PathCached, PathUpstream}
+
+// bucketWidth is the granularity of the rolling window.
+const bucketWidth = time.Minute
+
+// shortWindow is the fast-burn window reported alongside the full window.
+const shortWindow = 5 * time.Minute
+
+// latencyBounds are the histogram upper bounds, in seconds, used for metrics.
+var latencyBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Objective is a latency service level objective: Target of requests must
+// succeed within Threshold.
+type Objective struct {
+	Threshold time.Duration
+	Target    float64
+}
+
+// Report is a point-in-time view of SLO compliance for every path.
+type Report struct {
+	Window string       `json:"window"`
+	Paths  []PathReport `json:"paths"`
+}
+
+// PathReport summarises one path over the rolling windows.
+type PathReport struct {
+	Path                 Path           `json:"path"`
+	ThresholdMillis      float64        `json:"thresholdMs"`
+	Target               float64        `json:"target"`
+	Windows              []WindowReport `json:"windows"`
+	ErrorBudgetRemaining float64        `json:"errorBudgetRemaining"`
+}
+
+// WindowReport holds compliance figures for a single rolling window.
+type WindowReport struct {
+	Window     string  `json:"window"`
+	Total      uint64  `json:"total"`
+	Good       uint64  `json:"good"`
+	Compliance float64 `json:"compliance"`
+	BurnRate   float64 `json:"burnRate"`
+	P50Millis  float64 `json:"p50Ms"`
+	P99Millis  float64 `json:"p99Ms"`
+}
+
+// bucket accumulates observations for one minute.
+type bucket struct {
+	minute    int64
+	total     uint64
+	good      uint64
+	histogram []uint64
+}
+
+// series holds the rolling buckets and lifetime histogram for one path.
+type series struct {
+	objective Objective
+	buckets   []bucket
+	histogram []uint64
+	count     uint64
+	sum       float64
+}
+
+// Tracker records request latencies and computes rolling SLO compliance.
+type Tracker struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	window time.Duration
+	series map[Path]*series
+}
+
+// NewTracker creates a tracker evaluating objectives over a rolling window.
+func NewTracker(window time.Duration, objectives map[Path]Objective) *Tracker {
+	if window < bucketWidth {
+		window = bucketWidth
+	}
+	t := &Tracker{
+		now:    time.Now,
+		window: window,
+		series: make(map[Path]*series),
+	}
+	for _, p := range paths {
+		t.series[p] = &series{
+			objective: objectives[p],
+			buckets:   make([]bucket, int(window/bucketWidth)),
+			histogram: make([]uint64, len(latencyBounds)),
+		}
+	}
+	return t
+}
+
+// Observe records one request. Failed requests always count against the SLO.
+func (t *Tracker) Observe(path Path, latency time.Duration, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[path]
+	if !ok {
+		return
+	}
+	seconds := latency.Seconds()
+	s.count++
+	s.sum += seconds
+
+	minute := t.now().Unix() / int64(bucketWidth/time.Second)
+	b := &s.buckets[minute%int64(len(s.buckets))]
+	if b.minute != minute || b.histogram == nil {
+		*b = bucket{minute: minute, histogram: make([]uint64, len(latencyBounds)+1)}
+	}
+	b.total++
+	if !failed && latency <= s.objective.Threshold {
+		b.good++
+	}
+
+	// The rolling histogram is per-bucket; the lifetime one is cumulative as
+	// Prometheus expects.
+	b.histogram[bucketIndex(seconds)]++
+	for i, bound := range latencyBounds {
+		if seconds <= bound {
+			s.histogram[i]++
+		}
+	}
+}
+
+// bucketIndex returns the histogram slot for a latency, with the final slot
+// collecting everything above the largest bound.
+func bucketIndex(seconds float64) int {
+	return sort.SearchFloat64s(latencyBounds, seconds)
+}
+
+// Report returns compliance and error-budget burn for each path.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := Report{Window: t.window.String()}
+	nowMinute := t.now().Unix() / int64(bucketWidth/time.Second)
+	for _, p := range paths {
+		s := t.series[p]
+		pr := PathReport{
+			Path:            p,
+			ThresholdMillis: float64(s.objective.Threshold) / float64(time.Millisecond),
+			Target:          s.objective.Target,
+		}
+		for _, w := range []time.Duration{shortWindow, t.window} {
+			pr.Windows = append(pr.Windows, s.windowReport(w, nowMinute))
+		}
+		// Budget remaining over the full window: 1 means untouched, 0 exhausted.
+		full := pr.Windows[len(pr.Windows)-1]
+		pr.ErrorBudgetRemaining = 1 - full.BurnRate
+		report.Paths = append(report.Paths, pr)
+	}
+	return report
+}
+
+// windowReport aggregates the buckets that fall within window.
+func (s *series) windowReport(window time.Duration, nowMinute int64) WindowReport {
+	wr := WindowReport{Window: window.String()}
+	since := nowMinute - int64(window/bucketWidth)
+	histogram := make([]uint64, len(latencyBounds)+1)
+	for _, b := range s.buckets {
+		if b.minute > since && b.minute <= nowMinute && b.histogram != nil {
+			wr.Total += b.total
+			wr.Good += b.good
+			for i, n := range b.histogram {
+				histogram[i] += n
+			}
+		}
+	}
+	wr.Compliance = 1
+	if wr.Total > 0 {
+		wr.Compliance = float64(wr.Good) / float64(wr.Total)
+	}
+	if budget := 1 - s.objective.Target; budget > 0 {
+		wr.BurnRate = (1 - wr.Compliance) / budget
+	}
+	wr.P50Millis = percentile(histogram, wr.Total, 0.50) * 1000
+	wr.P99Millis = percentile(histogram, wr.Total, 0.99) * 1000
+	return wr
+}
+
+// percentile estimates the q-th quantile as the upper bound of the histogram
+// slot containing it. Values beyond the largest bound report that bound.
+func percentile(histogram []uint64, total uint64, q float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(total) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range histogram {
+		seen += n
+		if seen >= rank && i < len(latencyBounds) {
+			return latencyBounds[i]
+		}
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// WriteMetrics writes latency histograms and SLO gauges in the Prometheus text
+// exposition format.
+func (t *Tracker) WriteMetrics(w io.Writer) error {
+	report := t.Report()
+
+	t.mu.Lock()
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("# HELP emissions_request_duration_seconds Latency of measure requests by serving path.\n")
+	printf("# TYPE emissions_request_duration_seconds histogram\n")
+	for _, p := range paths {
+		s := t.series[p]
+		for i, bound := range latencyBounds {
+			printf("emissions_request_duration_seconds_bucket{path=%q,le=\"%g\"} %d\n", p, bound, s.histogram[i])
+		}
+		printf("emissions_request_duration_seconds_bucket{path=%q,le=\"+Inf\"} %d\n", p, s.count)
+		printf("emissions_request_duration_seconds_sum{path=%q} %g\n", p, s.sum)
+		printf("emissions_request_duration_seconds_count{path=%q} %d\n", p, s.count)
+	}
+	t.mu.Unlock()
+
+	printf("# HELP emissions_slo_target_ratio Target fraction of requests within the latency threshold.\n")
+	printf("# TYPE emissions_slo_target_ratio gauge\n")
+	for _, pr := range report.Paths {
+		printf("emissions_slo_target_ratio{path=%q} %g\n", pr.Path, pr.Target)
+	}
+	printf("# HELP emissions_slo_compliance_ratio Fraction of requests within the latency threshold.\n")
+	printf("# TYPE emissions_slo_compliance_ratio gauge\n")
+	for _, pr := range report.Paths {
+		for _, wr := range pr.Windows {
+			printf("emissions_slo_compliance_ratio{path=%q,window=%q} %g\n", pr.Path, wr.Window, wr.Compliance)
+		}
+	}
+	printf("# HELP emissions_slo_error_budget_burn_rate Rate at which the error budget is consumed (1 = on budget).\n")
+	printf("# TYPE emissions_slo_error_budget_burn_rate gauge\n")
+	for _, pr := range report.Paths {
+		for _, wr := range pr.Windows {
+			printf("emissions_slo_error_budget_burn_rate{path=%q,window=%q} %g\n", pr.Path, wr.Window, wr.BurnRate)
+		}
+	}
+	printf("# HELP emissions_slo_error_budget_remaining_ratio Fraction of the error budget left in the full window.\n")
+	printf("# TYPE emissions_slo_error_budget_remaining_ratio gauge\n")
+	for _, pr := range report.Paths {
+		printf("emissions_slo_error_budget_remaining_ratio{path=%q} %g\n", pr.Path, pr.ErrorBudgetRemaining)
+	}
+	return err
+}
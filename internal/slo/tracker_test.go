@@ -0,0 +1,89 @@
+package slo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/slo"
+)
+
+func newTracker() *slo.Tracker {
+	return slo.NewTracker(time.Hour, map[slo.Path]slo.Objective{
+		slo.PathCached:   {Threshold: 50 * time.Millisecond, Target: 0.9},
+		slo.PathUpstream: {Threshold: 2 * time.Second, Target: 0.5},
+	})
+}
+
+func findPath(t *testing.T, report slo.Report, path slo.Path) slo.PathReport {
+	t.Helper()
+	for _, pr := range report.Paths {
+		if pr.Path == path {
+			return pr
+		}
+	}
+	t.Fatalf("Expected path %s in report", path)
+	return slo.PathReport{}
+}
+
+func TestTrackerCompliance(t *testing.T) {
+	tracker := newTracker()
+
+	for i := 0; i < 8; i++ {
+		tracker.Observe(slo.PathCached, 10*time.Millisecond, false)
+	}
+	tracker.Observe(slo.PathCached, 80*time.Millisecond, false)
+	tracker.Observe(slo.PathCached, 5*time.Millisecond, true)
+	tracker.Observe(slo.PathUpstream, time.Second, false)
+
+	report := tracker.Report()
+	cached := findPath(t, report, slo.PathCached)
+	if len(cached.Windows) != 2 {
+		t.Fatalf("Expected 2 windows, got %d", len(cached.Windows))
+	}
+	for _, w := range cached.Windows {
+		if w.Total != 10 || w.Good != 8 {
+			t.Errorf("Expected 8 of 10 good requests in %s, got %d of %d", w.Window, w.Good, w.Total)
+		}
+		if w.Compliance != 0.8 {
+			t.Errorf("Expected compliance 0.8 in %s, got %v", w.Window, w.Compliance)
+		}
+		// 20% bad against a 10% budget burns at twice the sustainable rate.
+		if w.BurnRate < 1.99 || w.BurnRate > 2.01 {
+			t.Errorf("Expected burn rate 2 in %s, got %v", w.Window, w.BurnRate)
+		}
+		if w.P50Millis != 10 {
+			t.Errorf("Expected p50 of 10ms in %s, got %v", w.Window, w.P50Millis)
+		}
+	}
+	if cached.ErrorBudgetRemaining > -0.99 {
+		t.Errorf("Expected exhausted error budget, got %v", cached.ErrorBudgetRemaining)
+	}
+
+	upstream := findPath(t, report, slo.PathUpstream)
+	if upstream.Windows[0].Compliance != 1 || upstream.ErrorBudgetRemaining != 1 {
+		t.Errorf("Expected fully compliant upstream path, got %+v", upstream)
+	}
+}
+
+func TestTrackerWriteMetrics(t *testing.T) {
+	tracker := newTracker()
+	tracker.Observe(slo.PathCached, 10*time.Millisecond, false)
+
+	var buf bytes.Buffer
+	if err := tracker.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`emissions_request_duration_seconds_bucket{path="cached",le="0.01"} 1`,
+		`emissions_request_duration_seconds_count{path="cached"} 1`,
+		`emissions_slo_compliance_ratio{path="cached",window="5m0s"} 1`,
+		`emissions_slo_error_budget_remaining_ratio{path="upstream"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, out)
+		}
+	}
+}
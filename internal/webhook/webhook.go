@@ -0,0 +1,359 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"emissions-cache-service/internal/errors"
+	"emissions-cache-service/pkg/redact"
+
+	"github.com/google/uuid"
+)
+
+// Headers set on every delivery.
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// DeliveryStatus is the outcome of a delivery so far.
+type DeliveryStatus string
+
+const (
+	StatusPending   DeliveryStatus = "pending"
+	StatusDelivered DeliveryStatus = "delivered"
+	StatusFailed    DeliveryStatus = "failed"
+)
+
+// Delivery records the attempts to deliver one notification.
+type Delivery struct {
+	ID         string         `json:"id"`
+	JobID      string         `json:"jobId"`
+	URL        string         `json:"url"`
+	Event      string         `json:"event"`
+	Status     DeliveryStatus `json:"status"`
+	Attempts   int            `json:"attempts"`
+	StatusCode int            `json:"statusCode,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+}
+
+// Payload is the JSON body of a notification.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of the
+// timestamp, a dot and the body, prefixed with "sha256=".
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the timestamp and body.
+// Receivers should also reject timestamps that are too old to prevent replays.
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NotifierOption defines a functional option for configuring the Notifier.
+type NotifierOption func(*Notifier)
+
+// WithMaxAttempts sets how often a delivery is attempted before giving up.
+func WithMaxAttempts(attempts int) NotifierOption {
+	return func(n *Notifier) {
+		if attempts > 0 {
+			n.maxAttempts = attempts
+		}
+	}
+}
+
+// WithBackoff sets the wait before the first retry; it doubles on every retry.
+func WithBackoff(d time.Duration) NotifierOption {
+	return func(n *Notifier) {
+		if d > 0 {
+			n.backoff = d
+		}
+	}
+}
+
+// WithTimeout sets the timeout of each delivery attempt.
+func WithTimeout(d time.Duration) NotifierOption {
+	return func(n *Notifier) {
+		if d > 0 {
+			n.httpClient.Timeout = d
+		}
+	}
+}
+
+// WithAllowedHosts restricts callback URLs to the given hosts. An empty list
+// allows any host.
+func WithAllowedHosts(hosts []string) NotifierOption {
+	return func(n *Notifier) {
+		n.allowedHosts = make(map[string]bool, len(hosts))
+		for _, h := range hosts {
+			n.allowedHosts[strings.ToLower(h)] = true
+		}
+	}
+}
+
+// WithPrivateAddresses allows callbacks to loopback, private and link-local
+// addresses, which are refused by default so callers cannot reach internal
+// services or cloud metadata endpoints. Only use it when receivers run on
+// the same network and callers are trusted.
+func WithPrivateAddresses() NotifierOption {
+	return func(n *Notifier) {
+		n.allowPrivate = true
+	}
+}
+
+// WithLogSize sets how many deliveries are kept in the delivery log.
+func WithLogSize(size int) NotifierOption {
+	return func(n *Notifier) {
+		if size > 0 {
+			n.logSize = size
+		}
+	}
+}
+
+// Notifier sends signed notifications to callback URLs, retrying failed
+// deliveries, and keeps a log of recent deliveries.
+type Notifier struct {
+	secret       []byte
+	httpClient   *http.Client
+	maxAttempts  int
+	backoff      time.Duration
+	allowedHosts map[string]bool
+	allowPrivate bool
+	logSize      int
+	now          func() time.Time
+
+	mu         sync.Mutex
+	deliveries []*Delivery
+}
+
+// NewNotifier creates a Notifier that signs deliveries with secret.
+func NewNotifier(secret string, opts ...NotifierOption) *Notifier {
+	redact.AddSecret(secret)
+	n := &Notifier{
+		secret:      []byte(secret),
+		maxAttempts: 5,
+		backoff:     time.Second,
+		logSize:     1000,
+		now:         time.Now,
+	}
+	// Addresses are checked when connecting, after DNS resolution, so host
+	// names resolving to private addresses are refused too. Redirects are
+	// not followed, since they could lead anywhere.
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: n.checkAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	n.httpClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// ValidateURL checks that rawURL is an absolute http(s) URL to an allowed
+// host. Literal private addresses are rejected here; host names are checked
+// once resolved, when delivering.
+func (n *Notifier) ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.NewValidationError("callbackUrl must be an absolute http or https URL")
+	}
+	if len(n.allowedHosts) > 0 && !n.allowedHosts[strings.ToLower(u.Hostname())] {
+		return errors.NewValidationError(fmt.Sprintf("callbackUrl host %s is not allowed", u.Hostname()))
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !n.allowPrivate && privateIP(ip) {
+		return errors.NewValidationError(fmt.Sprintf("callbackUrl address %s is not allowed", u.Hostname()))
+	}
+	return nil
+}
+
+// errAddressNotAllowed is returned for deliveries to refused addresses, which
+// are not retried.
+var errAddressNotAllowed = stderrors.New("callback address is not allowed")
+
+// checkAddress refuses connections to private addresses unless they are
+// allowed. It is called by the dialer with the resolved address.
+func (n *Notifier) checkAddress(network, address string, _ syscall.RawConn) error {
+	if n.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || privateIP(ip) {
+		return fmt.Errorf("%w: %s", errAddressNotAllowed, host)
+	}
+	return nil
+}
+
+// privateIP reports whether ip is a loopback, private, link-local,
+// multicast or unspecified address.
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// Send delivers event with data to callbackURL on behalf of job jobID,
+// retrying until it is acknowledged with a 2xx status, the attempts are
+// exhausted or ctx is cancelled. It returns the final delivery record.
+func (n *Notifier) Send(ctx context.Context, jobID, callbackURL, event string, data interface{}) Delivery {
+	d := n.record(jobID, callbackURL, event)
+	body, err := json.Marshal(Payload{ID: d.ID, Event: event, CreatedAt: d.CreatedAt, Data: data})
+	if err != nil {
+		return n.finish(d, StatusFailed, 0, err)
+	}
+
+	backoff := n.backoff
+	for {
+		code, err := n.post(ctx, d, body)
+		n.update(d, func() {
+			d.Attempts++
+			d.StatusCode = code
+			d.Error = ""
+			if err != nil {
+				d.Error = redact.String(err.Error())
+			}
+		})
+		if err == nil {
+			return n.finish(d, StatusDelivered, code, nil)
+		}
+		if !retryable(code) || stderrors.Is(err, errAddressNotAllowed) || d.Attempts >= n.maxAttempts {
+			return n.finish(d, StatusFailed, code, err)
+		}
+		select {
+		case <-ctx.Done():
+			return n.finish(d, StatusFailed, code, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Deliveries returns the logged deliveries for jobID, oldest first.
+func (n *Notifier) Deliveries(jobID string) []Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	list := []Delivery{}
+	for _, d := range n.deliveries {
+		if d.JobID == jobID {
+			list = append(list, *d)
+		}
+	}
+	return list
+}
+
+// post makes one delivery attempt, returning the response status code. Any
+// status other than 2xx is reported as an error.
+func (n *Notifier) post(ctx context.Context, d *Delivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(n.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EmissionsService-Webhook/1.0")
+	req.Header.Set(HeaderID, d.ID)
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(n.secret, timestamp, body))
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt with the given status code (0
+// for transport errors) may succeed when repeated.
+func retryable(code int) bool {
+	return code == 0 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// record adds a pending delivery to the log, dropping the oldest entry when
+// the log is full.
+func (n *Notifier) record(jobID, callbackURL, event string) *Delivery {
+	now := n.now().UTC()
+	d := &Delivery{
+		ID:        uuid.New().String(),
+		JobID:     jobID,
+		URL:       callbackURL,
+		Event:     event,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.deliveries) >= n.logSize {
+		n.deliveries = n.deliveries[1:]
+	}
+	n.deliveries = append(n.deliveries, d)
+	return d
+}
+
+// finish records the final outcome of d and returns a copy of it.
+func (n *Notifier) finish(d *Delivery, status DeliveryStatus, code int, err error) Delivery {
+	n.update(d, func() {
+		d.Status = status
+		d.StatusCode = code
+		d.Error = ""
+		if err != nil {
+			d.Error = redact.String(err.Error())
+		}
+	})
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return *d
+}
+
+// update applies fn to d while holding the log lock.
+func (n *Notifier) update(d *Delivery, fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fn()
+	d.UpdatedAt = n.now().UTC()
+}
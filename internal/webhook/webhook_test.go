@@ -0,0 +1,155 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"emissions-cache-service/internal/webhook"
+)
+
+const testSecret = "webhook-test-secret"
+
+func TestSendSignsPayload(t *testing.T) {
+	var received webhook.Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify([]byte(testSecret), r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(webhook.HeaderEvent) != "job.succeeded" {
+			t.Errorf("unexpected event header %q", r.Header.Get(webhook.HeaderEvent))
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// Test receivers listen on loopback.
+	n := webhook.NewNotifier(testSecret, webhook.WithPrivateAddresses())
+	d := n.Send(context.Background(), "job-1", srv.URL, "job.succeeded", map[string]string{"id": "job-1"})
+	if d.Status != webhook.StatusDelivered || d.Attempts != 1 || d.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+	if received.ID != d.ID || received.Event != "job.succeeded" {
+		t.Errorf("unexpected payload: %+v", received)
+	}
+}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	n := webhook.NewNotifier(testSecret, webhook.WithPrivateAddresses(), webhook.WithBackoff(time.Millisecond))
+	d := n.Send(context.Background(), "job-1", srv.URL, "job.failed", nil)
+	if d.Status != webhook.StatusDelivered || d.Attempts != 3 {
+		t.Fatalf("expected delivery on the third attempt, got %+v", d)
+	}
+
+	log := n.Deliveries("job-1")
+	if len(log) != 1 || log[0].ID != d.ID || log[0].Status != webhook.StatusDelivered {
+		t.Errorf("unexpected delivery log: %+v", log)
+	}
+	if other := n.Deliveries("job-2"); len(other) != 0 {
+		t.Errorf("expected no deliveries for another job, got %+v", other)
+	}
+}
+
+func TestSendGivesUp(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	n := webhook.NewNotifier(testSecret, webhook.WithPrivateAddresses(), webhook.WithBackoff(time.Millisecond), webhook.WithMaxAttempts(2))
+	if d := n.Send(context.Background(), "job-1", srv.URL, "job.failed", nil); d.Status != webhook.StatusFailed || d.Attempts != 2 {
+		t.Errorf("expected failure after 2 attempts, got %+v", d)
+	}
+	if d := n.Send(context.Background(), "job-2", srv.URL+"/gone", "job.failed", nil); d.Status != webhook.StatusFailed || d.Attempts != 1 || d.StatusCode != http.StatusGone {
+		t.Errorf("expected client errors not to be retried, got %+v", d)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	n := webhook.NewNotifier(testSecret, webhook.WithAllowedHosts([]string{"hooks.example.com"}))
+	tests := map[string]bool{
+		"https://hooks.example.com/jobs":  true,
+		"http://HOOKS.example.com:8080/x": true,
+		"https://evil.example.com/jobs":   false,
+		"ftp://hooks.example.com/jobs":    false,
+		"/relative/path":                  false,
+	}
+	for url, valid := range tests {
+		if err := n.ValidateURL(url); (err == nil) != valid {
+			t.Errorf("ValidateURL(%q) error = %v, want valid %v", url, err, valid)
+		}
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+
+	n := webhook.NewNotifier(testSecret, webhook.WithBackoff(time.Millisecond), webhook.WithMaxAttempts(3))
+	for _, url := range []string{"http://127.0.0.1/jobs", "http://169.254.169.254/latest/meta-data", "http://10.0.0.1/jobs", "http://[::1]/jobs"} {
+		if err := n.ValidateURL(url); err == nil {
+			t.Errorf("ValidateURL(%q) = nil, want an error", url)
+		}
+	}
+
+	// Host names are checked once resolved, so the delivery itself is refused.
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	if d := n.Send(context.Background(), "job-1", url, "job.succeeded", nil); d.Status != webhook.StatusFailed || d.Attempts != 1 {
+		t.Errorf("expected a single refused attempt, got %+v", d)
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("expected no request to reach the receiver, got %d", got)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var followed int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			atomic.AddInt32(&followed, 1)
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	n := webhook.NewNotifier(testSecret, webhook.WithPrivateAddresses(), webhook.WithMaxAttempts(1))
+	d := n.Send(context.Background(), "job-1", srv.URL+"/jobs", "job.succeeded", nil)
+	if d.Status != webhook.StatusFailed || d.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("expected the redirect to fail the delivery, got %+v", d)
+	}
+	if got := atomic.LoadInt32(&followed); got != 0 {
+		t.Errorf("expected the redirect not to be followed, got %d requests", got)
+	}
+}
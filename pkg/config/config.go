@@ -8,20 +8,240 @@ import (
 	"github.com/spf13/viper"
 )
 
+// Deployment environments.
+const (
+	// EnvironmentDevelopment exposes redacted error details to clients.
+	EnvironmentDevelopment = "development"
+	// EnvironmentProduction hides error details from clients.
+	EnvironmentProduction = "production"
+)
+
 // Config holds the configuration settings for the service.
 type Config struct {
-	Scope3 struct {
-		APIURL string `mapstructure:"api_url"`
-		Token  string `mapstructure:"token"`
+	Environment string `mapstructure:"environment"`
+	Scope3      struct {
+		APIURL      string `mapstructure:"api_url"`
+		Token       string `mapstructure:"token" redact:"true"`
+		TokenSource struct {
+			// Type is static (the token above), env, file or command.
+			Type     string   `mapstructure:"type"`
+			Env      string   `mapstructure:"env"`
+			File     string   `mapstructure:"file"`
+			Command  []string `mapstructure:"command"`
+			CacheTTL string   `mapstructure:"cache_ttl"`
+		} `mapstructure:"token_source"`
 	} `mapstructure:"scope3"`
 	Server struct {
-		Port int    `mapstructure:"port"`
-		Host string `mapstructure:"host"`
+		Port      int    `mapstructure:"port"`
+		Host      string `mapstructure:"host"`
+		AdminPort int    `mapstructure:"admin_port"`
+		AdminHost string `mapstructure:"admin_host"`
+		// GRPCPort serves the gRPC API on Host when non-zero.
+		GRPCPort int `mapstructure:"grpc_port"`
+		// MaxBodyBytes and MaxRows limit the size of measure requests.
+		MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+		MaxRows      int   `mapstructure:"max_rows"`
+		// ValidateRequests rejects requests that do not match the OpenAPI
+		// document served at /v1/openapi.json.
+		ValidateRequests bool `mapstructure:"validate_requests"`
+		// Stream limits NDJSON measure requests, which are resolved
+		// ChunkSize rows at a time.
+		Stream struct {
+			MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+			MaxRows      int   `mapstructure:"max_rows"`
+			ChunkSize    int   `mapstructure:"chunk_size"`
+		} `mapstructure:"stream"`
+		AccessLog struct {
+			Enabled      bool     `mapstructure:"enabled"`
+			Format       string   `mapstructure:"format"`
+			SampleRate   float64  `mapstructure:"sample_rate"`
+			ExcludePaths []string `mapstructure:"exclude_paths"`
+		} `mapstructure:"access_log"`
+		TLS struct {
+			Enabled        bool     `mapstructure:"enabled"`
+			CertFile       string   `mapstructure:"cert_file"`
+			KeyFile        string   `mapstructure:"key_file"`
+			ReloadInterval string   `mapstructure:"reload_interval"`
+			ClientCAFile   string   `mapstructure:"client_ca_file"`
+			ClientAuth     string   `mapstructure:"client_auth"`
+			ClientScopes   []string `mapstructure:"client_scopes"`
+		} `mapstructure:"tls"`
+		CORS struct {
+			Enabled          bool     `mapstructure:"enabled"`
+			AllowedOrigins   []string `mapstructure:"allowed_origins"`
+			AllowedMethods   []string `mapstructure:"allowed_methods"`
+			AllowedHeaders   []string `mapstructure:"allowed_headers"`
+			ExposedHeaders   []string `mapstructure:"exposed_headers"`
+			AllowCredentials bool     `mapstructure:"allow_credentials"`
+			MaxAge           string   `mapstructure:"max_age"`
+		} `mapstructure:"cors"`
 	} `mapstructure:"server"`
 	Cache struct {
 		DefaultTTL      string `mapstructure:"default_ttl"`
 		CleanupInterval string `mapstructure:"cleanup_interval"`
 	} `mapstructure:"cache"`
+	Tenants []Tenant `mapstructure:"tenants"`
+	Auth    struct {
+		Enabled        bool     `mapstructure:"enabled"`
+		ExemptPaths    []string `mapstructure:"exempt_paths"`
+		APIKeys        []APIKey `mapstructure:"api_keys"`
+		APIKeysFile    string   `mapstructure:"api_keys_file"`
+		ReloadInterval string   `mapstructure:"reload_interval"`
+		JWT            struct {
+			JWKS            string `mapstructure:"jwks"`
+			Issuer          string `mapstructure:"issuer"`
+			Audience        string `mapstructure:"audience"`
+			Leeway          string `mapstructure:"leeway"`
+			RefreshInterval string `mapstructure:"refresh_interval"`
+		} `mapstructure:"jwt"`
+	} `mapstructure:"auth"`
+	RateLimit struct {
+		Enabled               bool    `mapstructure:"enabled"`
+		RequestsPerSecond     float64 `mapstructure:"requests_per_second"`
+		Burst                 int     `mapstructure:"burst"`
+		UpstreamRowsPerMinute float64 `mapstructure:"upstream_rows_per_minute"`
+		UpstreamBurst         int     `mapstructure:"upstream_burst"`
+	} `mapstructure:"rate_limit"`
+	Priority struct {
+		Enabled             bool             `mapstructure:"enabled"`
+		Mode                string           `mapstructure:"mode"`
+		MaxEntries          int              `mapstructure:"max_entries"`
+		AllowedInventoryIDs []string         `mapstructure:"allowed_inventory_ids"`
+		Clients             []PriorityClient `mapstructure:"clients"`
+	} `mapstructure:"priority"`
+	Audit struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Path    string `mapstructure:"path"`
+	} `mapstructure:"audit"`
+	SLO struct {
+		Window            string  `mapstructure:"window"`
+		CachedThreshold   string  `mapstructure:"cached_threshold"`
+		CachedTarget      float64 `mapstructure:"cached_target"`
+		UpstreamThreshold string  `mapstructure:"upstream_threshold"`
+		UpstreamTarget    float64 `mapstructure:"upstream_target"`
+	} `mapstructure:"slo"`
+	Jobs struct {
+		Enabled       bool   `mapstructure:"enabled"`
+		Workers       int    `mapstructure:"workers"`
+		ChunkSize     int    `mapstructure:"chunk_size"`
+		MaxQueued     int    `mapstructure:"max_queued"`
+		MaxQueuedRows int    `mapstructure:"max_queued_rows"`
+		MaxRows       int    `mapstructure:"max_rows"`
+		MaxBodyBytes  int64  `mapstructure:"max_body_bytes"`
+		Retention     string `mapstructure:"retention"`
+	} `mapstructure:"jobs"`
+	// Idempotency replays responses to submissions repeated with the same
+	// Idempotency-Key for Window, keeping at most MaxEntries of them in at
+	// most MaxBytes.
+	Idempotency struct {
+		Enabled    bool   `mapstructure:"enabled"`
+		Window     string `mapstructure:"window"`
+		MaxEntries int    `mapstructure:"max_entries"`
+		MaxBytes   int64  `mapstructure:"max_bytes"`
+	} `mapstructure:"idempotency"`
+	Webhooks struct {
+		Enabled        bool     `mapstructure:"enabled"`
+		Secret         string   `mapstructure:"secret" redact:"true"`
+		MaxAttempts    int      `mapstructure:"max_attempts"`
+		InitialBackoff string   `mapstructure:"initial_backoff"`
+		Timeout        string   `mapstructure:"timeout"`
+		AllowedHosts   []string `mapstructure:"allowed_hosts"`
+		AllowPrivate   bool     `mapstructure:"allow_private_addresses"`
+		LogSize        int      `mapstructure:"log_size"`
+	} `mapstructure:"webhooks"`
+}
+
+// APIKey registers the SHA-256 hash of a client's API key.
+type APIKey struct {
+	ClientID string   `mapstructure:"client_id"`
+	KeyHash  string   `mapstructure:"key_hash" redact:"true"`
+	Scopes   []string `mapstructure:"scopes"`
+	Tenant   string   `mapstructure:"tenant"`
+	// CallbackURL is notified when the client's batch jobs finish.
+	CallbackURL string `mapstructure:"callback_url"`
+}
+
+// Tenant overrides cache behaviour for one tenant.
+type Tenant struct {
+	ID string `mapstructure:"id"`
+	// DefaultTTL replaces cache.default_ttl for the tenant's entries when set.
+	DefaultTTL string `mapstructure:"default_ttl"`
+	// MaxEntries caps the tenant's cache entries; 0 means unlimited.
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// PriorityClient overrides the priority policy for a single client.
+type PriorityClient struct {
+	ClientID            string   `mapstructure:"client_id"`
+	MaxEntries          int      `mapstructure:"max_entries"`
+	AllowedInventoryIDs []string `mapstructure:"allowed_inventory_ids"`
+}
+
+// setDefaults registers fallback values for optional settings so that existing
+// configuration files keep working.
+func setDefaults() {
+	viper.SetDefault("environment", EnvironmentProduction)
+	viper.SetDefault("scope3.token_source.type", "static")
+	viper.SetDefault("scope3.token_source.env", "SCOPE3_API_TOKEN")
+	viper.SetDefault("scope3.token_source.cache_ttl", "5m")
+	viper.SetDefault("server.admin_host", "127.0.0.1")
+	viper.SetDefault("server.max_body_bytes", 1<<20)
+	viper.SetDefault("server.max_rows", 1000)
+	viper.SetDefault("server.validate_requests", true)
+	viper.SetDefault("server.stream.max_body_bytes", 64<<20)
+	viper.SetDefault("server.stream.max_rows", 100000)
+	viper.SetDefault("server.stream.chunk_size", 500)
+	viper.SetDefault("server.access_log.enabled", true)
+	viper.SetDefault("server.access_log.format", "combined")
+	viper.SetDefault("server.access_log.sample_rate", 1.0)
+	viper.SetDefault("server.access_log.exclude_paths", []string{"/v1/health"})
+	viper.SetDefault("server.tls.enabled", false)
+	viper.SetDefault("server.cors.enabled", false)
+	viper.SetDefault("server.cors.allowed_methods", []string{"GET", "POST", "DELETE"})
+	viper.SetDefault("server.cors.allowed_headers", []string{"Content-Type", "Authorization", "X-API-Key", "Idempotency-Key"})
+	viper.SetDefault("server.cors.exposed_headers", []string{"X-Request-ID", "Retry-After", "Idempotent-Replayed"})
+	viper.SetDefault("server.cors.max_age", "10m")
+	viper.SetDefault("server.tls.reload_interval", "1m")
+	viper.SetDefault("server.tls.client_auth", "require")
+	viper.SetDefault("server.tls.client_scopes", []string{"emissions:measure"})
+	viper.SetDefault("auth.enabled", false)
+	viper.SetDefault("auth.exempt_paths", []string{"/v1/health", "/v1/openapi.json"})
+	viper.SetDefault("auth.reload_interval", "10s")
+	viper.SetDefault("auth.jwt.leeway", "30s")
+	viper.SetDefault("auth.jwt.refresh_interval", "15m")
+	viper.SetDefault("rate_limit.enabled", false)
+	viper.SetDefault("rate_limit.requests_per_second", 10.0)
+	viper.SetDefault("rate_limit.burst", 20)
+	viper.SetDefault("rate_limit.upstream_rows_per_minute", 6000.0)
+	viper.SetDefault("rate_limit.upstream_burst", 10000)
+	viper.SetDefault("priority.enabled", false)
+	viper.SetDefault("priority.mode", "reject")
+	viper.SetDefault("priority.max_entries", 100)
+	viper.SetDefault("audit.enabled", false)
+	viper.SetDefault("audit.path", "audit.jsonl")
+	viper.SetDefault("slo.window", "1h")
+	viper.SetDefault("slo.cached_threshold", "50ms")
+	viper.SetDefault("slo.cached_target", 0.99)
+	viper.SetDefault("slo.upstream_threshold", "2s")
+	viper.SetDefault("slo.upstream_target", 0.95)
+	viper.SetDefault("jobs.enabled", false)
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.chunk_size", 500)
+	viper.SetDefault("jobs.max_queued", 100)
+	viper.SetDefault("jobs.max_queued_rows", 500000)
+	viper.SetDefault("jobs.max_rows", 100000)
+	viper.SetDefault("jobs.max_body_bytes", 64<<20)
+	viper.SetDefault("jobs.retention", "24h")
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.window", "24h")
+	viper.SetDefault("idempotency.max_entries", 10000)
+	viper.SetDefault("idempotency.max_bytes", 64<<20)
+	viper.SetDefault("webhooks.enabled", false)
+	viper.SetDefault("webhooks.max_attempts", 5)
+	viper.SetDefault("webhooks.initial_backoff", "1s")
+	viper.SetDefault("webhooks.timeout", "10s")
+	viper.SetDefault("webhooks.allow_private_addresses", false)
+	viper.SetDefault("webhooks.log_size", 1000)
 }
 
 // LoadConfig reads configuration from the specified file, expanding environment variables.
@@ -35,6 +255,7 @@ func LoadConfig(path string) (*Config, error) {
 	expandedContent := os.ExpandEnv(string(b))
 
 	// Set the config type and read from the expanded content.
+	setDefaults()
 	viper.SetConfigType("yaml")
 	err = viper.ReadConfig(strings.NewReader(expandedContent))
 	if err != nil {
@@ -50,6 +271,11 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// IsDevelopment reports whether the service runs in development mode.
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == EnvironmentDevelopment
+}
+
 // GetCacheTTL returns the TTL duration for cache entries.
 func (c *Config) GetCacheTTL() (time.Duration, error) {
 	return time.ParseDuration(c.Cache.DefaultTTL)
@@ -59,3 +285,68 @@ func (c *Config) GetCacheTTL() (time.Duration, error) {
 func (c *Config) GetCleanupInterval() (time.Duration, error) {
 	return time.ParseDuration(c.Cache.CleanupInterval)
 }
+
+// GetSLOWindow returns the rolling window over which SLO compliance is computed.
+func (c *Config) GetSLOWindow() (time.Duration, error) {
+	return time.ParseDuration(c.SLO.Window)
+}
+
+// GetSLOCachedThreshold returns the latency objective for cache-only requests.
+func (c *Config) GetSLOCachedThreshold() (time.Duration, error) {
+	return time.ParseDuration(c.SLO.CachedThreshold)
+}
+
+// GetSLOUpstreamThreshold returns the latency objective for requests that call Scope3.
+func (c *Config) GetSLOUpstreamThreshold() (time.Duration, error) {
+	return time.ParseDuration(c.SLO.UpstreamThreshold)
+}
+
+// GetTokenCacheTTL returns how long a token obtained from a command is reused.
+func (c *Config) GetTokenCacheTTL() (time.Duration, error) {
+	return time.ParseDuration(c.Scope3.TokenSource.CacheTTL)
+}
+
+// GetCORSMaxAge returns how long browsers may cache preflight responses.
+func (c *Config) GetCORSMaxAge() (time.Duration, error) {
+	return time.ParseDuration(c.Server.CORS.MaxAge)
+}
+
+// GetTLSReloadInterval returns how often the TLS certificate files are checked for changes.
+func (c *Config) GetTLSReloadInterval() (time.Duration, error) {
+	return time.ParseDuration(c.Server.TLS.ReloadInterval)
+}
+
+// GetJobRetention returns how long finished batch jobs and their results are kept.
+func (c *Config) GetJobRetention() (time.Duration, error) {
+	return time.ParseDuration(c.Jobs.Retention)
+}
+
+// GetIdempotencyWindow returns how long responses to idempotent submissions are kept.
+func (c *Config) GetIdempotencyWindow() (time.Duration, error) {
+	return time.ParseDuration(c.Idempotency.Window)
+}
+
+// GetWebhookBackoff returns the wait before the first retry of a failed webhook delivery.
+func (c *Config) GetWebhookBackoff() (time.Duration, error) {
+	return time.ParseDuration(c.Webhooks.InitialBackoff)
+}
+
+// GetWebhookTimeout returns the timeout of each webhook delivery attempt.
+func (c *Config) GetWebhookTimeout() (time.Duration, error) {
+	return time.ParseDuration(c.Webhooks.Timeout)
+}
+
+// GetAuthReloadInterval returns how often the API keys file is checked for changes.
+func (c *Config) GetAuthReloadInterval() (time.Duration, error) {
+	return time.ParseDuration(c.Auth.ReloadInterval)
+}
+
+// GetJWTLeeway returns the clock skew tolerated when checking token lifetimes.
+func (c *Config) GetJWTLeeway() (time.Duration, error) {
+	return time.ParseDuration(c.Auth.JWT.Leeway)
+}
+
+// GetJWKSRefreshInterval returns how often the JWKS is reloaded.
+func (c *Config) GetJWKSRefreshInterval() (time.Duration, error) {
+	return time.ParseDuration(c.Auth.JWT.RefreshInterval)
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"emissions-cache-service/pkg/redact"
+)
+
+// RedactedValue replaces secret values in configuration dumps.
+const RedactedValue = redact.Mask
+
+// Redacted returns the configuration as a nested map keyed by the same names
+// used in config.yaml, with every field tagged `redact:"true"` masked and
+// credential-shaped values in other strings, such as token command
+// arguments, masked too.
+func (c *Config) Redacted() map[string]interface{} {
+	return redactStruct(reflect.ValueOf(*c))
+}
+
+// redactStruct converts a struct into a map, masking secret fields.
+func redactStruct(v reflect.Value) map[string]interface{} {
+	out := make(map[string]interface{}, v.NumField())
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		out[name] = redactValue(v.Field(i), field.Tag.Get("redact") == "true")
+	}
+	return out
+}
+
+// redactValue renders a single configuration value, masking it when secret.
+func redactValue(v reflect.Value, secret bool) interface{} {
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactValue(v.Index(i), secret)
+		}
+		return items
+	case reflect.Map:
+		items := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items[iter.Key().String()] = redactValue(iter.Value(), secret)
+		}
+		return items
+	}
+	if secret && !v.IsZero() {
+		return RedactedValue
+	}
+	if v.Kind() == reflect.String {
+		return redact.String(v.String())
+	}
+	return v.Interface()
+}
@@ -0,0 +1,29 @@
+package config_test
+
+import (
+	"testing"
+
+	"emissions-cache-service/pkg/config"
+)
+
+func TestRedacted(t *testing.T) {
+	var cfg config.Config
+	cfg.Scope3.APIURL = "https://api.scope3.com/v2"
+	cfg.Scope3.Token = "super-secret"
+	cfg.Server.Port = 8080
+
+	dump := cfg.Redacted()
+	scope3, ok := dump["scope3"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected scope3 section keyed by its config name, got %v", dump)
+	}
+	if scope3["token"] != config.RedactedValue {
+		t.Errorf("Expected token to be redacted, got %v", scope3["token"])
+	}
+	if scope3["api_url"] != cfg.Scope3.APIURL {
+		t.Errorf("Expected api_url to be preserved, got %v", scope3["api_url"])
+	}
+	if server := dump["server"].(map[string]interface{}); server["port"] != 8080 {
+		t.Errorf("Expected port 8080, got %v", server["port"])
+	}
+}
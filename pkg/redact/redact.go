@@ -0,0 +1,88 @@
+// Package redact masks credentials in text destined for logs, error messages
+// and diagnostics.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask replaces redacted values.
+const Mask = "[REDACTED]"
+
+// maxSnippet is the longest upstream body kept by Snippet.
+const maxSnippet = 256
+
+// minSecretLength avoids masking short registered values that would match
+// unrelated text.
+const minSecretLength = 8
+
+// patterns match credentials by shape. The first group, when present, is kept.
+var patterns = []*regexp.Regexp{
+	// Authorization header values.
+	regexp.MustCompile(`(?i)(\b(?:bearer|basic)\s+)[A-Za-z0-9\-._~+/]+=*`),
+	// JSON Web Tokens appearing anywhere.
+	regexp.MustCompile(`()\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	// key=value, key: value and "key":"value" pairs with credential-like keys.
+	regexp.MustCompile(`(?i)((?:access[_-]?token|refresh[_-]?token|token|api[_-]?key|secret|client[_-]?secret|password)["']?\s*[:=]\s*["']?)[^"'\s&,;}]+`),
+}
+
+var (
+	mu      sync.RWMutex
+	secrets = make(map[string]struct{})
+)
+
+// AddSecret registers a known secret, such as a configured token, so it is
+// masked wherever it appears. Values shorter than eight characters are ignored.
+func AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	mu.Lock()
+	secrets[secret] = struct{}{}
+	mu.Unlock()
+}
+
+// String masks registered secrets and credential-shaped values in s.
+func String(s string) string {
+	mu.RLock()
+	for secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	mu.RUnlock()
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, "${1}"+Mask)
+	}
+	return s
+}
+
+// Snippet returns a redacted, truncated excerpt of an upstream response body,
+// suitable for including in an error.
+func Snippet(body []byte) string {
+	s := String(string(body))
+	if len(s) > maxSnippet {
+		s = s[:maxSnippet] + "...(truncated)"
+	}
+	return s
+}
+
+// writer redacts everything written through it.
+type writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a writer that redacts each write before passing it to w.
+// Loggers write whole lines, so values are never split across writes.
+func NewWriter(w io.Writer) io.Writer {
+	return &writer{w: w}
+}
+
+// Write redacts p and writes it, reporting the original length on success.
+func (rw *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
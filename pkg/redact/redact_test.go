@@ -0,0 +1,56 @@
+package redact_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"emissions-cache-service/pkg/redact"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bearer header", "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"jwt", "token eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJ4In0.c2ln rejected", "token [REDACTED] rejected"},
+		{"json field", `{"error":"bad","access_token":"s3cr3t"}`, `{"error":"bad","access_token":"[REDACTED]"}`},
+		{"query parameter", "/v1/health?api_key=abc&x=1", "/v1/health?api_key=[REDACTED]&x=1"},
+		{"plain text", "failed to fetch emissions", "failed to fetch emissions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redact.String(tt.in); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddSecret(t *testing.T) {
+	redact.AddSecret("short")
+	redact.AddSecret("registered-secret-value")
+	got := redact.String("short text with registered-secret-value inside")
+	if got != "short text with [REDACTED] inside" {
+		t.Errorf("Unexpected redaction: %q", got)
+	}
+}
+
+func TestSnippetTruncates(t *testing.T) {
+	got := redact.Snippet([]byte(strings.Repeat("a", 1000)))
+	if len(got) > 300 || !strings.HasSuffix(got, "(truncated)") {
+		t.Errorf("Expected a truncated snippet, got %d bytes", len(got))
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(redact.NewWriter(&buf), "", 0)
+	logger.Printf("calling upstream with Bearer %s", "tok3n")
+	if got := buf.String(); got != "calling upstream with Bearer [REDACTED]\n" {
+		t.Errorf("Unexpected log line: %q", got)
+	}
+}